@@ -11,16 +11,21 @@ import (
 
 	"delayed-notifier/config"
 	"delayed-notifier/internal/cache"
+	"delayed-notifier/internal/events"
 	"delayed-notifier/internal/handler"
+	"delayed-notifier/internal/metrics"
 	"delayed-notifier/internal/models"
 	"delayed-notifier/internal/queue"
+	"delayed-notifier/internal/ratelimit"
 	"delayed-notifier/internal/repository"
 	"delayed-notifier/internal/sender"
 	"delayed-notifier/internal/server"
 	"delayed-notifier/internal/service"
+	"delayed-notifier/internal/ticket"
 
 	_ "github.com/jackc/pgx/v5/stdlib"
 	"github.com/wb-go/wbf/dbpg"
+	"github.com/wb-go/wbf/retry"
 )
 
 func main() {
@@ -42,52 +47,231 @@ func main() {
 
 	// Инициализация репозитория
 	notifRepo := repository.NewPostgresNotificationRepo(dbConn)
+	deadLetterRepo := repository.NewPostgresDeadLetterRepo(dbConn)
+	telegramChatRepo := repository.NewPostgresTelegramChatRepo(dbConn)
+	templateRepo := repository.NewPostgresTemplateRepo(dbConn)
+	profileRepo := repository.NewPostgresUserProfileRepo(dbConn)
+	outboxRepo := repository.NewPostgresOutboxRepo(dbConn)
+	apiKeyRepo := repository.NewPostgresAPIKeyRepo(dbConn)
 
-	// Telegram
+	// Шаблоны сообщений
+	templateService := service.NewTemplateService(templateRepo)
+
+	// Telegram: без токена бот не запускается, канал отклоняет уведомления
 	token := cfg.TG_BOT_TOKEN
-	telegramSender, err := sender.NewTelegramSender(token)
+	telegramSender, err := sender.NewTelegramSender(token, telegramChatRepo)
 	if err != nil {
 		log.Fatal(err)
 	}
 	go telegramSender.ListenAndServe()
 
-	emailSender := sender.NewEmailSender(
-		cfg.SMTP_HOST,
-		cfg.SMTP_PORT,
-		cfg.SMTP_USERNAME,
-		cfg.SMTP_PASSWORD,
-		cfg.SMTP_FROM,
-	)
-
-	consoleSender := &sender.NativeSender{}
+	// Отправители каналов: настоящие или записывающие уведомления в память.
+	// Каналы, для которых отправитель не зарегистрирован, отклоняются при создании уведомления.
+	senders := make(map[models.ChannelType]sender.Sender)
+	var mailer *sender.EmailSender
+	switch cfg.SenderBackend {
+	case "live":
+		senders[models.Native] = &sender.NativeSender{}
+		mailer, err = sender.NewEmailSender(sender.EmailConfig{
+			Host:              cfg.SMTP_HOST,
+			Port:              cfg.SMTP_PORT,
+			Username:          cfg.SMTP_USERNAME,
+			Password:          cfg.SMTP_PASSWORD,
+			From:              cfg.SMTP_FROM,
+			Encryption:        cfg.SMTPEncryption,
+			PoolSize:          cfg.SMTPPoolSize,
+			IdleTimeout:       cfg.SMTPIdleTimeout,
+			AttachmentTimeout: cfg.AttachmentTimeout,
+			MaxAttachmentSize: int64(cfg.AttachmentMaxSize),
+			AttachmentHosts:   cfg.AttachmentHosts,
+		})
+		if err != nil {
+			log.Fatalf("Invalid SMTP config: %v", err)
+		}
+		senders[models.Email] = mailer
+		senders[models.Telegram] = telegramSender
+		senders[models.Webhook] = sender.NewWebhookSender(cfg.WebhookSecret, cfg.WebhookTimeout)
+		if cfg.WebhookSecret == "" {
+			log.Println("warning: WEBHOOK_SECRET is empty, webhook signatures are not secret")
+		}
+		if cfg.SMSGatewayURL != "" {
+			senders[sender.SMS], err = sender.NewSMSSender(sender.SMSConfig{
+				GatewayURL: cfg.SMSGatewayURL,
+				Token:      cfg.SMSGatewayToken,
+				From:       cfg.SMSFrom,
+				Timeout:    cfg.SMSTimeout,
+			})
+			if err != nil {
+				log.Fatalf("Invalid SMS config: %v", err)
+			}
+		}
+	case "recording":
+		for _, channel := range []models.ChannelType{models.Native, models.Email, models.Telegram, models.Webhook, sender.SMS} {
+			senders[channel] = sender.NewRecordingSender(channel.String())
+		}
+	default:
+		log.Fatalf("Unknown sender backend %q", cfg.SenderBackend)
+	}
 
-	multiSender := sender.NewMultiSender(consoleSender, emailSender, telegramSender)
+	registry := sender.NewRegistry(templateService)
+	for channel, s := range senders {
+		if err := registry.Register(channel, s); err != nil {
+			log.Fatalf("Failed to register %s sender: %v", channel, err)
+		}
+	}
+	log.Printf("Channels: %v", registry.Channels())
 
-	notificationQueue, err := queue.NewQueue(cfg.RabbitMQURL)
+	channelLimits, err := ratelimit.ParseChannelLimits(cfg.RateLimitChannels)
+	if err != nil {
+		log.Fatalf("Invalid RATE_LIMIT_CHANNELS: %v", err)
+	}
+	recipientLimit, err := ratelimit.ParseLimit(cfg.RateLimitRecipient)
 	if err != nil {
-		log.Fatalf("Failed to init RabbitMQ: %v", err)
+		log.Fatalf("Invalid RATE_LIMIT_RECIPIENT: %v", err)
 	}
-	defer notificationQueue.Close()
-	notificationQueue.StartMainConsumer()
 
-	// Инициализация кеша
-	redisCache := cache.NewCache(cfg.REDIS_ADDR, cfg.REDIS_PASSWORD, 0)
+	// Проверки зависимостей для /healthz и /readyz
+	healthChecks := map[string]handler.HealthCheck{
+		"postgres": func(ctx context.Context) error {
+			return dbConn.Master.PingContext(ctx)
+		},
+	}
 
-	// Сервис
-	notifService := service.NewNotificationService(notifRepo, redisCache, notificationQueue, multiSender)
+	// Кэш, лимиты отправки, события и билеты потока событий: в Redis (общие для всех реплик) или в памяти процесса
+	var (
+		notifCache    cache.NotifCache
+		limiter       ratelimit.Backend
+		eventBroker   events.Bus
+		streamTickets ticket.Store
+	)
+	switch cfg.CacheBackend {
+	case "redis":
+		redisCache := cache.NewCache(cfg.REDIS_ADDR, cfg.REDIS_PASSWORD, 0)
+		healthChecks["redis"] = redisCache.Ping
+		notifCache = redisCache
+		limiter = ratelimit.NewLimiter(redisCache.Client(), channelLimits, recipientLimit)
+		eventBroker = events.NewBroker(redisCache.Client())
+		streamTickets = ticket.NewRedisStore(redisCache.Client())
+	case "memory":
+		notifCache = cache.NewMemoryCache()
+		limiter = ratelimit.NewMemoryLimiter(channelLimits, recipientLimit)
+		eventBroker = events.NewMemoryBroker()
+		streamTickets = ticket.NewMemoryStore()
+	default:
+		log.Fatalf("Unknown cache backend %q", cfg.CacheBackend)
+	}
+	limitedSender := sender.NewRateLimitedSender(registry, limiter)
 
-	// ctx := context.Background()
-	if err := notifService.RestoreCacheFromDB(ctx); err != nil {
-		log.Printf("Failed to restore cache: %v", err)
+	// Планировщик отложенной доставки
+	var notificationQueue queue.Scheduler
+	switch cfg.SchedulerBackend {
+	case "postgres":
+		notificationQueue = queue.NewPostgresScheduler(notifRepo, queue.PostgresSchedulerConfig{
+			PollInterval: cfg.PollInterval,
+			BatchSize:    cfg.PollBatchSize,
+			Workers:      cfg.Workers,
+			Lease:        cfg.PollLease,
+		})
+	case "rabbitmq":
+		rabbitQueue, err := queue.NewQueue(cfg.RabbitMQURL, cfg.Workers)
+		if err != nil {
+			log.Fatalf("Failed to init RabbitMQ: %v", err)
+		}
+		healthChecks["rabbitmq"] = func(ctx context.Context) error {
+			return rabbitQueue.Ping()
+		}
+		notificationQueue = rabbitQueue
+	case "memory":
+		notificationQueue = queue.NewMemoryScheduler(cfg.Workers)
+	default:
+		log.Fatalf("Unknown scheduler backend %q", cfg.SchedulerBackend)
 	}
 
-	notificationQueue.SetHandler(func(ctx context.Context, notif models.Notification) error {
+	// События об изменении уведомлений. Свой контекст: потоки SSE
+	// нужно закрыть раньше, чем остановится HTTP сервер.
+	eventsCtx, stopEvents := context.WithCancel(ctx)
+	go eventBroker.Run(eventsCtx)
+
+	// Сервис
+	notifService := service.NewNotificationService(notifRepo, deadLetterRepo, profileRepo, notifCache, notificationQueue, limitedSender, retry.Strategy{
+		Attempts: cfg.RetryAttempts,
+		Delay:    cfg.RetryDelay,
+		Backoff:  cfg.RetryBackoff,
+	}, eventBroker)
+
+	// Сверка кэша с Postgres: первый проход сразу, затем периодически
+	cacheReconciler := service.NewCacheReconciler(notifRepo, notifCache, service.CacheReconcilerConfig{
+		Interval:  cfg.CacheReconcileInterval,
+		BatchSize: cfg.CacheReconcileBatchSize,
+	})
+	go cacheReconciler.Run(ctx)
+
+	if err := notificationQueue.Consume(func(ctx context.Context, notif models.Notification) error {
 		return notifService.ProcessNotification(ctx, &notif)
+	}); err != nil {
+		log.Fatalf("Failed to start consumer: %v", err)
+	}
+
+	// Публикация уведомлений, записанных в outbox вместе с ними
+	// Отложенные сообщения живут только в брокере или в памяти; для postgres очередью служит сама таблица
+	lostAfter := cfg.OutboxLostAfter
+	if cfg.SchedulerBackend == "postgres" {
+		lostAfter = 0
+	}
+	outboxRelay := service.NewOutboxRelay(outboxRepo, notificationQueue, notifCache, service.OutboxRelayConfig{
+		Interval:      cfg.OutboxInterval,
+		BatchSize:     cfg.OutboxBatchSize,
+		MaxBackoff:    cfg.OutboxMaxBackoff,
+		SweepInterval: cfg.OutboxSweepInterval,
+		LostAfter:     lostAfter,
+		Retention:     cfg.OutboxRetention,
+	})
+	go outboxRelay.Run(ctx)
+
+	// DLQ брокера сохраняем в БД, чтобы их можно было просмотреть и отправить повторно
+	if dlq, ok := notificationQueue.(queue.DeadLetterSource); ok {
+		if err := dlq.ConsumeDeadLetters(notifService.StoreDeadLetter); err != nil {
+			log.Fatalf("Failed to start DLQ consumer: %v", err)
+		}
+	}
+
+	// Метрики, которые считаются запросом к БД при каждом сборе
+	metrics.RegisterGauge("dead_letters", "Number of notifications in the dead letter table.", func(ctx context.Context) (float64, error) {
+		n, err := deadLetterRepo.Count(ctx)
+		return float64(n), err
+	})
+	metrics.RegisterGauge("outbox_pending", "Number of outbox messages not yet published.", func(ctx context.Context) (float64, error) {
+		st, err := outboxRepo.Stats(ctx)
+		if err != nil {
+			return 0, err
+		}
+		return float64(st.Pending), nil
+	})
+	metrics.RegisterGauge("outbox_oldest_seconds", "Age of the oldest unpublished outbox message.", func(ctx context.Context) (float64, error) {
+		st, err := outboxRepo.Stats(ctx)
+		if err != nil || st.Oldest == nil {
+			return 0, err
+		}
+		return time.Since(*st.Oldest).Seconds(), nil
 	})
 
 	// Handler
 	notifHandler := handler.NewNotificationHandler(notifService)
-	router := server.NewRouter(notifHandler)
+	tgHandler := handler.NewTelegramHandler(telegramSender)
+	tplHandler := handler.NewTemplateHandler(templateService)
+	adminHandler := handler.NewAdminHandler(limiter, outboxRelay)
+	eventsHandler := handler.NewEventsHandler(eventBroker)
+	healthHandler := handler.NewHealthHandler(healthChecks)
+
+	// API-ключи арендаторов
+	if !cfg.AuthEnabled {
+		log.Println("warning: AUTH_ENABLED=false, API is open to everyone")
+	} else if cfg.AdminToken == "" {
+		log.Println("warning: ADMIN_TOKEN is empty, admin routes and key management are disabled")
+	}
+	authHandler := handler.NewAuthHandler(service.NewAPIKeyService(apiKeyRepo, streamTickets), cfg.AdminToken, cfg.AuthEnabled)
+
+	router := server.NewRouter(notifHandler, tgHandler, tplHandler, adminHandler, eventsHandler, healthHandler, authHandler)
 	httpServer := server.NewHTTPServer(cfg, router)
 
 	// Graceful shutdown
@@ -109,11 +293,17 @@ func main() {
 	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer shutdownCancel()
 
+	// Закрываем потоки событий, иначе Shutdown будет ждать их до таймаута
+	stopEvents()
+
 	// Закрываем HTTP сервер
 	if err := httpServer.Shutdown(shutdownCtx); err != nil {
 		log.Printf("HTTP server shutdown error: %v", err)
 	}
 
+	// Останавливаем outbox relay до закрытия очереди
+	cancel()
+
 	// Останавливаем очередь
 	if err := notificationQueue.Close(); err != nil {
 		log.Printf("Queue shutdown error: %v", err)
@@ -122,8 +312,15 @@ func main() {
 	// Останавливаем Telegram
 	telegramSender.Stop()
 
-	// Закрываем Redis кеш
-	if err := redisCache.Close(); err != nil {
+	// Закрываем SMTP-соединения
+	if mailer != nil {
+		if err := mailer.Close(); err != nil {
+			log.Printf("SMTP shutdown error: %v", err)
+		}
+	}
+
+	// Закрываем кеш
+	if err := notifCache.Close(); err != nil {
 		log.Printf("Cache shutdown error: %v", err)
 	}
 
@@ -0,0 +1,77 @@
+// Локальный SMS-шлюз для проверки канала sms: принимает сообщения так же,
+// как настоящий шлюз (POST /messages), и показывает последние на GET /messages.
+// FAIL_RATE (0..1) — доля запросов, на которые шлюз отвечает 503.
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"delayed-notifier/internal/sender"
+)
+
+// Сколько последних сообщений хранить
+const keep = 100
+
+type received struct {
+	sender.SMSMessage
+	ReceivedAt time.Time `json:"received_at"`
+}
+
+func main() {
+	addr := os.Getenv("ADDR")
+	if addr == "" {
+		addr = ":9090"
+	}
+	failRate, _ := strconv.ParseFloat(os.Getenv("FAIL_RATE"), 64)
+	token := os.Getenv("TOKEN")
+
+	var (
+		lock     sync.Mutex
+		messages []received
+	)
+
+	http.HandleFunc("POST /messages", func(w http.ResponseWriter, r *http.Request) {
+		if token != "" && r.Header.Get("Authorization") != "Bearer "+token {
+			http.Error(w, "invalid token", http.StatusUnauthorized)
+			return
+		}
+
+		var msg sender.SMSMessage
+		if err := json.NewDecoder(r.Body).Decode(&msg); err != nil || msg.To == "" {
+			http.Error(w, "invalid message", http.StatusBadRequest)
+			return
+		}
+		if rand.Float64() < failRate {
+			http.Error(w, "gateway is temporarily unavailable", http.StatusServiceUnavailable)
+			return
+		}
+
+		lock.Lock()
+		messages = append(messages, received{SMSMessage: msg, ReceivedAt: time.Now()})
+		if len(messages) > keep {
+			messages = messages[len(messages)-keep:]
+		}
+		lock.Unlock()
+
+		log.Printf("SMS %s to %s: %s", msg.ID, msg.To, msg.Text)
+		w.WriteHeader(http.StatusAccepted)
+	})
+
+	http.HandleFunc("GET /messages", func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(messages)
+	})
+
+	log.Printf("Fake SMS gateway listening on %s", addr)
+	log.Fatal(http.ListenAndServe(addr, nil))
+}
@@ -3,6 +3,8 @@ package config
 import (
 	"os"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -19,6 +21,63 @@ type Config struct {
 	SMTP_FROM      string
 	REDIS_ADDR     string
 	REDIS_PASSWORD string
+
+	// SMTP: шифрование ("starttls", "ssl", "none"), пул keep-alive соединений
+	// и ограничения на вложения, скачиваемые по URL
+	SMTPEncryption    string
+	SMTPPoolSize      int
+	SMTPIdleTimeout   time.Duration
+	AttachmentTimeout time.Duration
+	AttachmentMaxSize int
+	AttachmentHosts   []string
+
+	// Планировщик отложенной доставки: "rabbitmq", "postgres" или "memory"
+	SchedulerBackend string
+	PollInterval     time.Duration
+	PollBatchSize    int
+	PollLease        time.Duration
+
+	// Кэш, лимиты и события: "redis" или "memory" (один процесс, без Redis)
+	CacheBackend string
+	// Как часто и какими пачками сверять кэш уведомлений с Postgres
+	CacheReconcileInterval  time.Duration
+	CacheReconcileBatchSize int
+	// Отправители: "live" или "recording" (уведомления только записываются в память)
+	SenderBackend string
+
+	// Доставка: число параллельных воркеров и стратегия повторов
+	Workers       int
+	RetryAttempts int
+	RetryDelay    time.Duration
+	RetryBackoff  float64
+
+	// Webhook: секрет для HMAC-подписи и таймаут запроса
+	WebhookSecret  string
+	WebhookTimeout time.Duration
+
+	// SMS: адрес HTTP-шлюза (пусто — канал sms не подключается), токен, отправитель и таймаут
+	SMSGatewayURL   string
+	SMSGatewayToken string
+	SMSFrom         string
+	SMSTimeout      time.Duration
+
+	// Лимиты отправки: "telegram=30:30,email=10" и "rate:burst" на одного получателя
+	RateLimitChannels  string
+	RateLimitRecipient string
+
+	// Авторизация: API-ключи арендаторов и токен для административных маршрутов
+	AuthEnabled bool
+	AdminToken  string
+
+	// Outbox: как часто и какими пачками публиковать сообщения в очередь,
+	// предельная задержка между повторами, через сколько считать сообщение
+	// потерянным брокером (0 — не проверять) и сколько хранить опубликованные
+	OutboxInterval      time.Duration
+	OutboxBatchSize     int
+	OutboxMaxBackoff    time.Duration
+	OutboxSweepInterval time.Duration
+	OutboxLostAfter     time.Duration
+	OutboxRetention     time.Duration
 }
 
 func Load() (*Config, error) {
@@ -39,6 +98,50 @@ func Load() (*Config, error) {
 		SMTP_FROM:      getEnv("SMTP_FROM", ""),
 		REDIS_ADDR:     getEnv("REDIS_ADDR", "redis:6379"),
 		REDIS_PASSWORD: getEnv("REDIS_PASSWORD", ""),
+
+		SMTPEncryption:    getEnv("SMTP_ENCRYPTION", "starttls"),
+		SMTPPoolSize:      getInt("SMTP_POOL_SIZE", 4),
+		SMTPIdleTimeout:   getDuration("SMTP_IDLE_TIMEOUT", 30*time.Second),
+		AttachmentTimeout: getDuration("EMAIL_ATTACHMENT_TIMEOUT", 15*time.Second),
+		AttachmentMaxSize: getInt("EMAIL_ATTACHMENT_MAX_SIZE", 10<<20),
+		AttachmentHosts:   getList("EMAIL_ATTACHMENT_HOSTS"),
+
+		SchedulerBackend: getEnv("SCHEDULER_BACKEND", "rabbitmq"),
+		PollInterval:     getDuration("POLL_INTERVAL", time.Second),
+		PollBatchSize:    getInt("POLL_BATCH_SIZE", 10),
+		PollLease:        getDuration("POLL_LEASE", 5*time.Minute),
+
+		CacheBackend:  getEnv("CACHE_BACKEND", "redis"),
+		SenderBackend: getEnv("SENDER_BACKEND", "live"),
+
+		CacheReconcileInterval:  getDuration("CACHE_RECONCILE_INTERVAL", 5*time.Minute),
+		CacheReconcileBatchSize: getInt("CACHE_RECONCILE_BATCH_SIZE", 500),
+
+		Workers:       getInt("WORKERS", 4),
+		RetryAttempts: getInt("RETRY_ATTEMPTS", 5),
+		RetryDelay:    getDuration("RETRY_DELAY", time.Minute),
+		RetryBackoff:  getFloat("RETRY_BACKOFF", 2),
+
+		WebhookSecret:  getEnv("WEBHOOK_SECRET", ""),
+		WebhookTimeout: getDuration("WEBHOOK_TIMEOUT", 10*time.Second),
+
+		SMSGatewayURL:   getEnv("SMS_GATEWAY_URL", ""),
+		SMSGatewayToken: getEnv("SMS_GATEWAY_TOKEN", ""),
+		SMSFrom:         getEnv("SMS_FROM", ""),
+		SMSTimeout:      getDuration("SMS_TIMEOUT", 10*time.Second),
+
+		RateLimitChannels:  getEnv("RATE_LIMIT_CHANNELS", "telegram=30:30"),
+		RateLimitRecipient: getEnv("RATE_LIMIT_RECIPIENT", "1:5"),
+
+		AuthEnabled: getBool("AUTH_ENABLED", true),
+		AdminToken:  getEnv("ADMIN_TOKEN", ""),
+
+		OutboxInterval:      getDuration("OUTBOX_INTERVAL", time.Second),
+		OutboxBatchSize:     getInt("OUTBOX_BATCH_SIZE", 100),
+		OutboxMaxBackoff:    getDuration("OUTBOX_MAX_BACKOFF", 5*time.Minute),
+		OutboxSweepInterval: getDuration("OUTBOX_SWEEP_INTERVAL", time.Minute),
+		OutboxLostAfter:     getDuration("OUTBOX_LOST_AFTER", 10*time.Minute),
+		OutboxRetention:     getDuration("OUTBOX_RETENTION", 24*time.Hour),
 	}
 
 	return cfg, nil
@@ -50,3 +153,42 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+// getList разбирает список через запятую; пустые элементы пропускаются
+func getList(key string) []string {
+	var list []string
+	for _, v := range strings.Split(os.Getenv(key), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+func getInt(key string, defaultValue int) int {
+	if value, err := strconv.Atoi(os.Getenv(key)); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
+func getDuration(key string, defaultValue time.Duration) time.Duration {
+	if value, err := time.ParseDuration(os.Getenv(key)); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
+func getFloat(key string, defaultValue float64) float64 {
+	if value, err := strconv.ParseFloat(os.Getenv(key), 64); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
+func getBool(key string, defaultValue bool) bool {
+	if value, err := strconv.ParseBool(os.Getenv(key)); err == nil {
+		return value
+	}
+	return defaultValue
+}
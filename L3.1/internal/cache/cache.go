@@ -4,19 +4,40 @@ import (
 	"context"
 	"encoding/json"
 	"strconv"
+	"time"
 
 	"delayed-notifier/internal/models"
 
 	"github.com/wb-go/wbf/redis"
 )
 
+// NotifCache — кэш уведомлений перед Postgres. Отсутствие записи ничего
+// не говорит о состоянии уведомления: запись могла истечь или быть вытеснена.
 type NotifCache interface {
 	Set(ctx context.Context, notif *models.Notification) error
 	Get(ctx context.Context, id int) (*models.Notification, error)
+	// GetMany возвращает найденные в кэше уведомления по ID
+	GetMany(ctx context.Context, ids []int) (map[int]*models.Notification, error)
 	Delete(ctx context.Context, id int) error
 	Close() error
 }
 
+const (
+	// scheduledGrace — сколько запланированное уведомление живёт в кэше после send_at:
+	// повторы и отложенные тихими часами отправки уходят позже
+	scheduledGrace = 24 * time.Hour
+	// finishedTTL — сколько живут отправленные и отменённые уведомления
+	finishedTTL = time.Hour
+)
+
+// TTL возвращает срок жизни записи уведомления в кэше
+func TTL(notif *models.Notification, now time.Time) time.Duration {
+	if notif.Status != models.Scheduled {
+		return finishedTTL
+	}
+	return max(notif.SendAt.Sub(now), 0) + scheduledGrace
+}
+
 type Cache struct {
 	client *redis.Client
 }
@@ -45,6 +66,35 @@ func (c *Cache) Get(ctx context.Context, id int) (*models.Notification, error) {
 	return &notif, nil
 }
 
+func (c *Cache) GetMany(ctx context.Context, ids []int) (map[int]*models.Notification, error) {
+	result := make(map[int]*models.Notification, len(ids))
+	if len(ids) == 0 {
+		return result, nil
+	}
+
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = strconv.Itoa(id)
+	}
+	vals, err := c.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	for i, val := range vals {
+		s, ok := val.(string)
+		if !ok {
+			continue // нет в кэше
+		}
+		var notif models.Notification
+		if err := json.Unmarshal([]byte(s), &notif); err != nil {
+			return nil, err
+		}
+		result[ids[i]] = &notif
+	}
+	return result, nil
+}
+
 func (c *Cache) Set(ctx context.Context, notif *models.Notification) error {
 	data, err := json.Marshal(notif)
 	if err != nil {
@@ -53,7 +103,7 @@ func (c *Cache) Set(ctx context.Context, notif *models.Notification) error {
 
 	key := notif.ID
 
-	return c.client.Set(ctx, key, data)
+	return c.client.SetWithExpiration(ctx, key, data, TTL(notif, time.Now()))
 }
 
 func (c *Cache) Delete(ctx context.Context, id int) error {
@@ -61,6 +111,16 @@ func (c *Cache) Delete(ctx context.Context, id int) error {
 	return c.client.Del(ctx, key)
 }
 
+// Client возвращает Redis-клиент кэша для других подсистем (лимиты, pub/sub)
+func (c *Cache) Client() *redis.Client {
+	return c.client
+}
+
+// Ping проверяет доступность Redis
+func (c *Cache) Ping(ctx context.Context) error {
+	return c.client.Ping(ctx).Err()
+}
+
 func (c *Cache) Close() error {
 	return c.client.Close()
 }
@@ -0,0 +1,101 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"sync"
+	"time"
+
+	"delayed-notifier/internal/models"
+)
+
+// MemoryCache хранит уведомления в памяти процесса. Как и Redis, держит
+// сериализованные копии, поэтому изменение полученного уведомления не меняет кэш.
+type MemoryCache struct {
+	lock  sync.RWMutex
+	items map[string]memoryItem
+	sets  int
+}
+
+type memoryItem struct {
+	data    []byte
+	expires time.Time
+}
+
+// Через сколько записей удалять истёкшие, заменяя EXPIRE из Redis-версии
+const sweepEvery = 1000
+
+var _ NotifCache = (*MemoryCache)(nil)
+
+func NewMemoryCache() *MemoryCache {
+	return &MemoryCache{
+		items: make(map[string]memoryItem),
+	}
+}
+
+func (c *MemoryCache) Get(ctx context.Context, id int) (*models.Notification, error) {
+	c.lock.RLock()
+	item, ok := c.items[strconv.Itoa(id)]
+	c.lock.RUnlock()
+
+	if !ok || time.Now().After(item.expires) {
+		return nil, nil // нет в кэше
+	}
+
+	var notif models.Notification
+	if err := json.Unmarshal(item.data, &notif); err != nil {
+		return nil, err
+	}
+
+	return &notif, nil
+}
+
+func (c *MemoryCache) GetMany(ctx context.Context, ids []int) (map[int]*models.Notification, error) {
+	result := make(map[int]*models.Notification, len(ids))
+	for _, id := range ids {
+		notif, err := c.Get(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if notif != nil {
+			result[id] = notif
+		}
+	}
+	return result, nil
+}
+
+func (c *MemoryCache) Set(ctx context.Context, notif *models.Notification) error {
+	data, err := json.Marshal(notif)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.items[notif.ID] = memoryItem{data: data, expires: now.Add(TTL(notif, now))}
+	if c.sets++; c.sets%sweepEvery == 0 {
+		for key, item := range c.items {
+			if now.After(item.expires) {
+				delete(c.items, key)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (c *MemoryCache) Delete(ctx context.Context, id int) error {
+	c.lock.Lock()
+	delete(c.items, strconv.Itoa(id))
+	c.lock.Unlock()
+
+	return nil
+}
+
+func (c *MemoryCache) Close() error {
+	return nil
+}
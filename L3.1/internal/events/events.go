@@ -0,0 +1,297 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"delayed-notifier/internal/models"
+
+	goredis "github.com/go-redis/redis/v8"
+	"github.com/wb-go/wbf/redis"
+)
+
+// Типы событий жизненного цикла уведомления
+const (
+	Created  = "created"
+	Updated  = "updated"
+	Sent     = "sent"
+	Failed   = "failed"
+	Canceled = "canceled"
+)
+
+// Event — изменение статуса уведомления. ID — идентификатор записи
+// в Redis Stream, по нему клиент возобновляет поток после переподключения.
+type Event struct {
+	ID             string             `json:"id"`
+	Type           string             `json:"type"`
+	NotificationID string             `json:"notification_id"`
+	UserID         string             `json:"user_id"`
+	TenantID       string             `json:"tenant_id,omitempty"`
+	Status         models.StatusType  `json:"status"`
+	Channel        models.ChannelType `json:"channel"`
+	At             time.Time          `json:"at"`
+}
+
+// NewEvent собирает событие по текущему состоянию уведомления
+func NewEvent(typ string, n *models.Notification) Event {
+	channel := n.Channel
+	if n.DeliveredChannel != nil {
+		channel = *n.DeliveredChannel
+	}
+
+	return Event{
+		Type:           typ,
+		NotificationID: n.ID,
+		UserID:         n.UserID,
+		TenantID:       n.TenantID,
+		Status:         n.Status,
+		Channel:        channel,
+		At:             time.Now(),
+	}
+}
+
+// Bus рассылает события и отдаёт их подписчикам.
+// Реализации: Broker (Redis, общий для реплик) и MemoryBroker (в памяти процесса).
+type Bus interface {
+	Publish(ctx context.Context, e Event) error
+	Subscribe(ctx context.Context, lastID string) (<-chan Event, func(), error)
+	// Run обслуживает подписчиков, пока не отменён ctx; затем закрывает их потоки
+	Run(ctx context.Context)
+}
+
+// ErrInvalidID возвращается, если Last-Event-ID не похож на идентификатор события
+var ErrInvalidID = errors.New("invalid event id")
+
+const (
+	streamKey = "notify:events"
+	channel   = "notify:events"
+	// Сколько последних событий хранится для возобновления потока
+	historySize = 10000
+	// Буфер подписчика; медленный клиент, переполнивший его, отключается
+	subscriberBuffer = 64
+)
+
+// Broker рассылает события всем репликам. Каждое событие пишется в ограниченный
+// Redis Stream (история для Last-Event-ID) и публикуется в pub/sub-канал.
+// Реплика держит одну подписку на канал и раздаёт события своим SSE-клиентам.
+type Broker struct {
+	client *redis.Client
+	hub    *hub
+}
+
+var _ Bus = (*Broker)(nil)
+
+func NewBroker(client *redis.Client) *Broker {
+	return &Broker{
+		client: client,
+		hub:    newHub(),
+	}
+}
+
+// Publish сохраняет событие в истории и рассылает его подписчикам всех реплик
+func (b *Broker) Publish(ctx context.Context, e Event) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	id, err := b.client.XAdd(ctx, &goredis.XAddArgs{
+		Stream: streamKey,
+		MaxLen: historySize,
+		Approx: true,
+		Values: map[string]any{"event": data},
+	}).Result()
+	if err != nil {
+		return err
+	}
+
+	e.ID = id
+	data, err = json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	return b.client.Publish(ctx, channel, data).Err()
+}
+
+// Run слушает pub/sub-канал и раздаёт события локальным подписчикам, пока не отменён ctx
+func (b *Broker) Run(ctx context.Context) {
+	sub := b.client.Subscribe(ctx, channel)
+	defer func() { _ = sub.Close() }()
+
+	messages := sub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			b.hub.closeAll()
+			return
+		case msg, ok := <-messages:
+			if !ok {
+				b.hub.closeAll()
+				return
+			}
+
+			var e Event
+			if err := json.Unmarshal([]byte(msg.Payload), &e); err != nil {
+				log.Printf("events: failed to decode event: %v", err)
+				continue
+			}
+			b.hub.broadcast(e)
+		}
+	}
+}
+
+// Subscribe возвращает поток событий. Если задан lastID, сначала отдаются
+// сохранённые события после него, затем новые. Поток закрывается вызовом
+// отписки, а также если подписчик не успевает читать события.
+func (b *Broker) Subscribe(ctx context.Context, lastID string) (<-chan Event, func(), error) {
+	if lastID != "" && !validID(lastID) {
+		return nil, nil, ErrInvalidID
+	}
+
+	live, unsubscribe := b.hub.add()
+	if lastID == "" {
+		return live, unsubscribe, nil
+	}
+
+	// Подписка оформлена до чтения истории, поэтому между ними ничего не теряется;
+	// события, попавшие и туда, и туда, отбрасываются по ID
+	history, err := b.client.XRange(ctx, streamKey, "("+lastID, "+").Result()
+	if err != nil {
+		unsubscribe()
+		return nil, nil, err
+	}
+
+	replay := make([]Event, 0, len(history))
+	for _, msg := range history {
+		raw, _ := msg.Values["event"].(string)
+		var e Event
+		if err := json.Unmarshal([]byte(raw), &e); err != nil {
+			continue
+		}
+		e.ID = msg.ID
+		replay = append(replay, e)
+	}
+
+	return resume(ctx, replay, live, lastID), unsubscribe, nil
+}
+
+// resume отдаёт сохранённые события, затем новые из live, пропуская уже отданные
+func resume(ctx context.Context, history []Event, live <-chan Event, lastID string) <-chan Event {
+	out := make(chan Event, subscriberBuffer)
+	go func() {
+		defer close(out)
+
+		last := lastID
+		for _, e := range history {
+			select {
+			case out <- e:
+				last = e.ID
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		for e := range live {
+			if !after(e.ID, last) {
+				continue
+			}
+			select {
+			case out <- e:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out
+}
+
+// hub раздаёт события подписчикам одной реплики
+type hub struct {
+	lock        sync.Mutex
+	subscribers map[chan Event]struct{}
+}
+
+func newHub() *hub {
+	return &hub{
+		subscribers: make(map[chan Event]struct{}),
+	}
+}
+
+func (h *hub) add() (chan Event, func()) {
+	live := make(chan Event, subscriberBuffer)
+	h.lock.Lock()
+	h.subscribers[live] = struct{}{}
+	h.lock.Unlock()
+
+	return live, func() {
+		h.lock.Lock()
+		defer h.lock.Unlock()
+		h.remove(live)
+	}
+}
+
+func (h *hub) remove(sub chan Event) {
+	if _, ok := h.subscribers[sub]; ok {
+		delete(h.subscribers, sub)
+		close(sub)
+	}
+}
+
+func (h *hub) broadcast(e Event) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	for sub := range h.subscribers {
+		select {
+		case sub <- e:
+		default:
+			// Клиент не успевает читать — отключаем, он переподключится с Last-Event-ID
+			h.remove(sub)
+		}
+	}
+}
+
+func (h *hub) closeAll() {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	for sub := range h.subscribers {
+		h.remove(sub)
+	}
+}
+
+// after сравнивает идентификаторы записей Redis Stream вида "<ms>-<seq>"
+func after(a, b string) bool {
+	am, as := splitID(a)
+	bm, bs := splitID(b)
+	if am != bm {
+		return am > bm
+	}
+	return as > bs
+}
+
+func validID(id string) bool {
+	ms, seq, ok := strings.Cut(id, "-")
+	if !ok {
+		return false
+	}
+	if _, err := strconv.ParseUint(ms, 10, 64); err != nil {
+		return false
+	}
+	_, err := strconv.ParseUint(seq, 10, 64)
+	return err == nil
+}
+
+func splitID(id string) (uint64, uint64) {
+	ms, seq, _ := strings.Cut(id, "-")
+	m, _ := strconv.ParseUint(ms, 10, 64)
+	s, _ := strconv.ParseUint(seq, 10, 64)
+	return m, s
+}
@@ -0,0 +1,75 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// MemoryBroker рассылает события внутри одного процесса. История хранится
+// в памяти; ID имеют тот же вид, что у Redis Stream, и начинаются с момента
+// запуска, так что Last-Event-ID от прошлого запуска не путается с новыми.
+type MemoryBroker struct {
+	hub     *hub
+	epoch   int64
+	seq     uint64
+	history []Event
+	lock    sync.Mutex
+}
+
+var _ Bus = (*MemoryBroker)(nil)
+
+func NewMemoryBroker() *MemoryBroker {
+	return &MemoryBroker{
+		hub:   newHub(),
+		epoch: time.Now().UnixMilli(),
+	}
+}
+
+func (b *MemoryBroker) Publish(ctx context.Context, e Event) error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.seq++
+	e.ID = fmt.Sprintf("%d-%d", b.epoch, b.seq)
+
+	b.history = append(b.history, e)
+	if len(b.history) > historySize {
+		b.history = b.history[len(b.history)-historySize:]
+	}
+
+	b.hub.broadcast(e)
+	return nil
+}
+
+// Subscribe снимает историю и регистрирует подписчика под одной блокировкой,
+// поэтому новые события не теряются и не дублируются
+func (b *MemoryBroker) Subscribe(ctx context.Context, lastID string) (<-chan Event, func(), error) {
+	if lastID != "" && !validID(lastID) {
+		return nil, nil, ErrInvalidID
+	}
+
+	b.lock.Lock()
+	live, unsubscribe := b.hub.add()
+	var replay []Event
+	if lastID != "" {
+		for _, e := range b.history {
+			if after(e.ID, lastID) {
+				replay = append(replay, e)
+			}
+		}
+	}
+	b.lock.Unlock()
+
+	if lastID == "" {
+		return live, unsubscribe, nil
+	}
+	return resume(ctx, replay, live, lastID), unsubscribe, nil
+}
+
+// Run закрывает потоки подписчиков после отмены ctx
+func (b *MemoryBroker) Run(ctx context.Context) {
+	<-ctx.Done()
+	b.hub.closeAll()
+}
@@ -0,0 +1,56 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+
+	"delayed-notifier/internal/ratelimit"
+	"delayed-notifier/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LimitStatus — источник текущих лимитов отправки
+type LimitStatus interface {
+	Status(ctx context.Context) (map[string]ratelimit.Status, ratelimit.Limit, error)
+}
+
+// OutboxStatus — источник метрик outbox
+type OutboxStatus interface {
+	Metrics(ctx context.Context) (*service.OutboxMetrics, error)
+}
+
+type AdminHandler struct {
+	limits LimitStatus
+	outbox OutboxStatus
+}
+
+func NewAdminHandler(limits LimitStatus, outbox OutboxStatus) *AdminHandler {
+	return &AdminHandler{
+		limits: limits,
+		outbox: outbox,
+	}
+}
+
+// GET /admin/limits
+func (h *AdminHandler) GetLimits(c *gin.Context) {
+	channels, recipient, err := h.limits.Status(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"channels":  channels,
+		"recipient": recipient,
+	})
+}
+
+// GET /admin/outbox
+func (h *AdminHandler) GetOutbox(c *gin.Context) {
+	metrics, err := h.outbox.Metrics(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, metrics)
+}
@@ -0,0 +1,187 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"delayed-notifier/internal/models"
+	"delayed-notifier/internal/service"
+	"delayed-notifier/internal/tenant"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AuthHandler проверяет API-ключи и управляет ими.
+// Административный токен открывает все маршруты и видит уведомления всех арендаторов.
+type AuthHandler struct {
+	svc        *service.APIKeyService
+	adminToken string
+	enabled    bool
+}
+
+func NewAuthHandler(svc *service.APIKeyService, adminToken string, enabled bool) *AuthHandler {
+	return &AuthHandler{
+		svc:        svc,
+		adminToken: adminToken,
+		enabled:    enabled,
+	}
+}
+
+// Require пропускает запросы с ключом, у которого есть право scope,
+// и выполняет их от имени арендатора ключа
+func (h *AuthHandler) Require(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !h.enabled {
+			c.Next()
+			return
+		}
+
+		key := requestKey(c)
+		if key == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "api key is required"})
+			return
+		}
+		if h.isAdmin(key) {
+			c.Next()
+			return
+		}
+
+		k, err := h.svc.Authenticate(c.Request.Context(), key)
+		if errors.Is(err, service.ErrInvalidAPIKey) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if !k.HasScope(scope) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "api key lacks scope " + scope})
+			return
+		}
+
+		c.Request = c.Request.WithContext(tenant.WithID(c.Request.Context(), k.TenantID))
+		c.Next()
+	}
+}
+
+// RequireAdmin пропускает только запросы с административным токеном
+func (h *AuthHandler) RequireAdmin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !h.enabled {
+			c.Next()
+			return
+		}
+		if h.adminToken == "" {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "admin api is disabled: ADMIN_TOKEN is not set"})
+			return
+		}
+		if !h.isAdmin(requestKey(c)) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "admin token is required"})
+			return
+		}
+		c.Next()
+	}
+}
+
+func (h *AuthHandler) isAdmin(key string) bool {
+	return h.adminToken != "" && subtle.ConstantTimeCompare([]byte(key), []byte(h.adminToken)) == 1
+}
+
+// RequireStream пропускает к потоку событий запросы с ключом, у которого есть право read,
+// или с одноразовым билетом в ?ticket= — EventSource не умеет задавать заголовки
+func (h *AuthHandler) RequireStream() gin.HandlerFunc {
+	read := h.Require(models.ScopeRead)
+	return func(c *gin.Context) {
+		ticket := c.Query("ticket")
+		if !h.enabled || ticket == "" {
+			read(c)
+			return
+		}
+
+		ctx, err := h.svc.RedeemStreamTicket(c.Request.Context(), ticket)
+		if errors.Is(err, service.ErrInvalidTicket) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+// requestKey достаёт ключ из X-API-Key или Authorization: Bearer.
+// Ключ в параметрах запроса не принимается: адреса попадают в журналы сервера и прокси.
+func requestKey(c *gin.Context) string {
+	if key := c.GetHeader("X-API-Key"); key != "" {
+		return key
+	}
+	if token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer "); ok {
+		return strings.TrimSpace(token)
+	}
+	return ""
+}
+
+// POST /notify/events/ticket
+// Билет действует 30 секунд и открывает поток событий один раз
+func (h *AuthHandler) IssueStreamTicket(c *gin.Context) {
+	t, err := h.svc.IssueStreamTicket(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, t)
+}
+
+// POST /admin/keys
+// Ключ возвращается только в этом ответе
+func (h *AuthHandler) IssueKey(c *gin.Context) {
+	var k models.APIKey
+	if err := c.ShouldBindJSON(&k); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.svc.Issue(c.Request.Context(), &k); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, k)
+}
+
+// GET /admin/keys?tenant_id=
+func (h *AuthHandler) ListKeys(c *gin.Context) {
+	keys, err := h.svc.ListKeys(c.Request.Context(), c.Query("tenant_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, keys)
+}
+
+// DELETE /admin/keys/:id
+func (h *AuthHandler) RevokeKey(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	if err := h.svc.Revoke(c.Request.Context(), id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "api key not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
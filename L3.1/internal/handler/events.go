@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"delayed-notifier/internal/events"
+	"delayed-notifier/internal/tenant"
+
+	"github.com/gin-gonic/gin"
+)
+
+// EventSource — поток событий об изменении уведомлений
+type EventSource interface {
+	Subscribe(ctx context.Context, lastID string) (<-chan events.Event, func(), error)
+}
+
+type EventsHandler struct {
+	source EventSource
+}
+
+func NewEventsHandler(source EventSource) *EventsHandler {
+	return &EventsHandler{
+		source: source,
+	}
+}
+
+// Период комментариев-пингов, чтобы прокси не закрывали простаивающее соединение
+const keepAliveInterval = 15 * time.Second
+
+// GET /notify/events?user_id=&ticket=
+// Server-Sent Events; после переподключения браузер сам присылает Last-Event-ID,
+// а при открытии потока по новому билету ID передаётся в ?last_event_id=
+func (h *EventsHandler) Stream(c *gin.Context) {
+	userID := c.Query("user_id")
+	lastID := c.GetHeader("Last-Event-ID")
+	if lastID == "" {
+		lastID = c.Query("last_event_id")
+	}
+
+	ctx := c.Request.Context()
+	stream, unsubscribe, err := h.source.Subscribe(ctx, lastID)
+	if errors.Is(err, events.ErrInvalidID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer unsubscribe()
+
+	// WriteTimeout сервера оборвал бы поток через несколько секунд
+	_ = http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	ping := time.NewTicker(keepAliveInterval)
+	defer ping.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-ping.C:
+			_, err := fmt.Fprint(w, ": ping\n\n")
+			return err == nil
+		case e, ok := <-stream:
+			if !ok {
+				// Поток закрыт сервером — клиент переподключится с Last-Event-ID
+				return false
+			}
+			if userID != "" && e.UserID != userID {
+				return true
+			}
+			if !tenant.Allows(ctx, e.TenantID) {
+				return true
+			}
+
+			data, err := json.Marshal(e)
+			if err != nil {
+				return true
+			}
+			_, err = fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", e.ID, e.Type, data)
+			return err == nil
+		}
+	})
+}
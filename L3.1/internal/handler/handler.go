@@ -1,8 +1,13 @@
 package handler
 
 import (
+	"database/sql"
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"delayed-notifier/internal/models"
 	"delayed-notifier/internal/service"
@@ -28,22 +33,136 @@ func (h *NotificationHandler) CreateNotification(c *gin.Context) {
 		return
 	}
 
-	if err := h.svc.CreateNotification(c, &n); err != nil {
+	if key := c.GetHeader("Idempotency-Key"); key != "" {
+		n.IdempotencyKey = key
+	}
+
+	created, err := h.svc.CreateNotification(c, &n)
+	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
+	// Повтор запроса с тем же ключом возвращает исходное уведомление
+	if !created {
+		c.JSON(http.StatusOK, n)
+		return
+	}
 	c.JSON(http.StatusCreated, n)
 }
 
-// GET /notify
+// POST /notify/batch
+func (h *NotificationHandler) CreateBatch(c *gin.Context) {
+	var items []*models.Notification
+	if err := c.ShouldBindJSON(&items); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	results, err := h.svc.CreateBatch(c.Request.Context(), items)
+	if errors.Is(err, service.ErrInvalidBatch) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"results": results})
+}
+
+// GET /notify?user_id=&status=&channel=&priority=&send_from=&send_to=&recipient=&cursor=&limit=
 func (h *NotificationHandler) ListNotifications(c *gin.Context) {
-	list, err := h.svc.ListNotifications(c.Request.Context())
+	filter, err := parseFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	page, err := h.svc.ListNotifications(c.Request.Context(), filter)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, list)
+	c.JSON(http.StatusOK, page)
+}
+
+var statusNames = map[string]models.StatusType{
+	"scheduled": models.Scheduled,
+	"sent":      models.Sent,
+	"failed":    models.Failed,
+	"canceled":  models.Canceled,
+	"fallback":  models.SentViaFallback,
+}
+
+func parseFilter(c *gin.Context) (models.NotificationFilter, error) {
+	f := models.NotificationFilter{
+		UserID:    c.Query("user_id"),
+		Recipient: c.Query("recipient"),
+	}
+
+	if f.UserID != "" {
+		if _, err := strconv.ParseInt(f.UserID, 10, 64); err != nil {
+			return f, fmt.Errorf("invalid user_id %q", f.UserID)
+		}
+	}
+
+	if v := c.Query("status"); v != "" {
+		status, ok := statusNames[strings.ToLower(v)]
+		if !ok {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return f, fmt.Errorf("invalid status %q", v)
+			}
+			status = models.StatusType(n)
+		}
+		f.Status = &status
+	}
+
+	if v := c.Query("channel"); v != "" {
+		channel, err := models.ParseChannel(v)
+		if err != nil {
+			return f, err
+		}
+		f.Channel = &channel
+	}
+
+	if v := c.Query("priority"); v != "" {
+		priority, err := models.ParsePriority(v)
+		if err != nil {
+			return f, err
+		}
+		f.Priority = &priority
+	}
+
+	for param, dst := range map[string]**time.Time{"send_from": &f.SendFrom, "send_to": &f.SendTo} {
+		if v := c.Query(param); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return f, fmt.Errorf("invalid %s: expected RFC3339 time", param)
+			}
+			*dst = &t
+		}
+	}
+
+	if v := c.Query("cursor"); v != "" {
+		cursor, err := models.DecodeCursor(v)
+		if err != nil {
+			return f, err
+		}
+		f.After = cursor
+	}
+
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return f, fmt.Errorf("invalid limit %q", v)
+		}
+		f.Limit = n
+	}
+
+	return f, nil
 }
 
 // GET /notify/:id
@@ -64,6 +183,71 @@ func (h *NotificationHandler) GetNotification(c *gin.Context) {
 	c.JSON(http.StatusOK, n)
 }
 
+// GET /notify/:id/occurrences
+func (h *NotificationHandler) ListOccurrences(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	list, err := h.svc.ListSeries(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "notification not found"})
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// GET /notify/:id/attempts
+func (h *NotificationHandler) ListAttempts(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	list, err := h.svc.ListAttempts(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "notification not found"})
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// PATCH /notify/:id
+func (h *NotificationHandler) UpdateNotification(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	var patch models.NotificationPatch
+	if err := c.ShouldBindJSON(&patch); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	n, err := h.svc.UpdateNotification(c.Request.Context(), id, patch)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			c.JSON(http.StatusNotFound, gin.H{"error": "notification not found"})
+		case errors.Is(err, service.ErrNotScheduled):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, n)
+}
+
 // DELETE /notify/:id
 func (h *NotificationHandler) CancelNotification(c *gin.Context) {
 	idStr := c.Param("id")
@@ -74,7 +258,14 @@ func (h *NotificationHandler) CancelNotification(c *gin.Context) {
 	}
 
 	if err := h.svc.CancelNotification(c, id); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			c.JSON(http.StatusNotFound, gin.H{"error": "notification not found"})
+		case errors.Is(err, service.ErrNotScheduled):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
 		return
 	}
 	c.Status(http.StatusNoContent)
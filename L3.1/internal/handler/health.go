@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HealthCheck проверяет доступность одной зависимости
+type HealthCheck func(ctx context.Context) error
+
+type HealthHandler struct {
+	checks map[string]HealthCheck
+}
+
+func NewHealthHandler(checks map[string]HealthCheck) *HealthHandler {
+	return &HealthHandler{
+		checks: checks,
+	}
+}
+
+const healthCheckTimeout = 2 * time.Second
+
+// run выполняет проверки параллельно и возвращает их результаты
+func (h *HealthHandler) run(ctx context.Context) (map[string]string, bool) {
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+
+	var (
+		lock    sync.Mutex
+		wg      sync.WaitGroup
+		results = make(map[string]string, len(h.checks))
+		healthy = true
+	)
+	for name, check := range h.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			status := "ok"
+			if err := check(ctx); err != nil {
+				status = err.Error()
+			}
+
+			lock.Lock()
+			defer lock.Unlock()
+			results[name] = status
+			if status != "ok" {
+				healthy = false
+			}
+		}()
+	}
+	wg.Wait()
+
+	return results, healthy
+}
+
+// GET /healthz
+// Процесс жив, пока отвечает; недоступность зависимостей видна в ответе,
+// но не делает его ошибкой, чтобы оркестратор не перезапускал сервис зря
+func (h *HealthHandler) Healthz(c *gin.Context) {
+	checks, healthy := h.run(c.Request.Context())
+
+	status := "ok"
+	if !healthy {
+		status = "degraded"
+	}
+	c.JSON(http.StatusOK, gin.H{"status": status, "checks": checks})
+}
+
+// GET /readyz
+// 503, пока хотя бы одна зависимость недоступна
+func (h *HealthHandler) Readyz(c *gin.Context) {
+	checks, healthy := h.run(c.Request.Context())
+
+	if !healthy {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "unavailable", "checks": checks})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok", "checks": checks})
+}
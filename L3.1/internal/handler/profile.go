@@ -0,0 +1,54 @@
+package handler
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"delayed-notifier/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GET /users/:id/profile
+func (h *NotificationHandler) GetProfile(c *gin.Context) {
+	userID := c.Param("id")
+	if _, err := strconv.ParseInt(userID, 10, 64); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	p, err := h.svc.GetProfile(c.Request.Context(), userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "profile not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, p)
+}
+
+// PUT /users/:id/profile
+func (h *NotificationHandler) SaveProfile(c *gin.Context) {
+	userID := c.Param("id")
+	if _, err := strconv.ParseInt(userID, 10, 64); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	var p models.UserProfile
+	if err := c.ShouldBindJSON(&p); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	p.UserID = userID
+
+	if err := h.svc.SaveProfile(c.Request.Context(), &p); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, p)
+}
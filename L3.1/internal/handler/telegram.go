@@ -0,0 +1,35 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+
+	"delayed-notifier/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TelegramRecipients — источник зарегистрированных в боте пользователей
+type TelegramRecipients interface {
+	Recipients(ctx context.Context) ([]*models.TelegramChat, error)
+}
+
+type TelegramHandler struct {
+	recipients TelegramRecipients
+}
+
+func NewTelegramHandler(recipients TelegramRecipients) *TelegramHandler {
+	return &TelegramHandler{
+		recipients: recipients,
+	}
+}
+
+// GET /telegram/recipients
+func (h *TelegramHandler) ListRecipients(c *gin.Context) {
+	list, err := h.recipients.Recipients(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
@@ -0,0 +1,97 @@
+package metrics
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "notifier"
+
+var (
+	// Notifications — переходы уведомлений (created, sent, failed, canceled...) по каналам
+	Notifications = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "notifications_total",
+		Help:      "Notification lifecycle events by type and channel.",
+	}, []string{"event", "channel"})
+
+	// SendDuration — длительность одной попытки отправки по каналу и результату
+	SendDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "send_duration_seconds",
+		Help:      "Duration of a single delivery attempt by channel and result.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"channel", "result"})
+
+	// SchedulingLag — насколько фактическая доставка отстала от send_at
+	SchedulingLag = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "scheduling_lag_seconds",
+		Help:      "Delay between send_at and successful delivery by channel.",
+		Buckets:   []float64{0.1, 0.5, 1, 5, 15, 30, 60, 300, 900, 3600},
+	}, []string{"channel"})
+
+	// Retries — повторные попытки, запланированные после неудачной отправки
+	Retries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "retries_total",
+		Help:      "Delivery retries scheduled after a failed attempt by channel.",
+	}, []string{"channel"})
+
+	// OutboxRelayed — попытки публикации сообщений из outbox по результату
+	OutboxRelayed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "outbox_relayed_total",
+		Help:      "Outbox publish attempts by result.",
+	}, []string{"result"})
+
+	// OutboxRequeued — запланированные уведомления, перепубликованные из-за потери брокером
+	OutboxRequeued = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "outbox_requeued_total",
+		Help:      "Scheduled notifications re-published because their broker message was lost.",
+	})
+
+	// CacheMismatches — расхождения кэша с Postgres: missing — записи нет в кэше,
+	// stale — в кэше устаревшее состояние, orphan — в кэше уведомление, которого нет в базе.
+	// source — кто заметил: reconciler или read (проверка перед отправкой).
+	CacheMismatches = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cache_mismatches_total",
+		Help:      "Notification cache entries that disagreed with Postgres and were repaired, by kind and source.",
+	}, []string{"kind", "source"})
+
+	// CacheReconcileRuns — проходы сверки кэша с Postgres по результату
+	CacheReconcileRuns = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cache_reconcile_runs_total",
+		Help:      "Cache reconciliation passes by result.",
+	}, []string{"result"})
+)
+
+// Таймаут запроса значения gauge при сборе метрик
+const gaugeTimeout = 2 * time.Second
+
+// RegisterGauge регистрирует gauge, значение которого запрашивается при каждом сборе метрик.
+// Если запрос не удался, отдаётся -1.
+func RegisterGauge(name, help string, value func(ctx context.Context) (float64, error)) {
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      name,
+		Help:      help,
+	}, func() float64 {
+		ctx, cancel := context.WithTimeout(context.Background(), gaugeTimeout)
+		defer cancel()
+
+		v, err := value(ctx)
+		if err != nil {
+			log.Printf("metrics: failed to collect %s: %v", name, err)
+			return -1
+		}
+		return v
+	})
+}
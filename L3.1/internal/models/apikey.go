@@ -0,0 +1,53 @@
+package models
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// Права API-ключа
+const (
+	ScopeCreate = "create"
+	ScopeRead   = "read"
+	ScopeUpdate = "update"
+	ScopeCancel = "cancel"
+)
+
+// Scopes — все права, которые можно выдать ключу
+var Scopes = []string{ScopeCreate, ScopeRead, ScopeUpdate, ScopeCancel}
+
+// APIKey — ключ доступа арендатора. В базе хранится только хеш ключа;
+// сам ключ возвращается один раз, при выпуске.
+type APIKey struct {
+	ID         string     `json:"id"`
+	TenantID   string     `json:"tenant_id" binding:"required"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes" binding:"required"`
+	Key        string     `json:"key,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// HasScope сообщает, выдано ли ключу право scope
+func (k *APIKey) HasScope(scope string) bool {
+	return slices.Contains(k.Scopes, scope)
+}
+
+// Validate проверяет арендатора и права ключа
+func (k *APIKey) Validate() error {
+	if k.TenantID == "" {
+		return fmt.Errorf("tenant_id is required")
+	}
+	if len(k.Scopes) == 0 {
+		return fmt.Errorf("at least one scope is required")
+	}
+	for _, s := range k.Scopes {
+		if !slices.Contains(Scopes, s) {
+			return fmt.Errorf("unknown scope %q, expected one of %v", s, Scopes)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package models
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// NotificationFilter — условия выборки списка уведомлений.
+// Пустые поля не ограничивают выборку.
+type NotificationFilter struct {
+	UserID    string
+	Status    *StatusType
+	Channel   *ChannelType
+	Priority  *Priority
+	SendFrom  *time.Time
+	SendTo    *time.Time
+	Recipient string // подстрока получателя, без учёта регистра
+	After     *Cursor
+	Limit     int
+}
+
+// NotificationPage — страница списка и курсор следующей страницы
+type NotificationPage struct {
+	Items      []*Notification `json:"items"`
+	NextCursor string          `json:"next_cursor,omitempty"`
+}
+
+// Cursor — позиция в списке, отсортированном по (created_at, id) по убыванию
+type Cursor struct {
+	CreatedAt time.Time
+	ID        int
+}
+
+// Encode упаковывает курсор в непрозрачную строку для клиента
+func (c Cursor) Encode() string {
+	raw := c.CreatedAt.UTC().Format(time.RFC3339Nano) + "|" + strconv.Itoa(c.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// DecodeCursor разбирает строку, полученную из Cursor.Encode
+func DecodeCursor(s string) (*Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+
+	ts, id, ok := strings.Cut(string(raw), "|")
+	if !ok {
+		return nil, errors.New("invalid cursor")
+	}
+
+	createdAt, err := time.Parse(time.RFC3339Nano, ts)
+	if err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+	n, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+
+	return &Cursor{CreatedAt: createdAt, ID: n}, nil
+}
@@ -1,6 +1,15 @@
 package models
 
-import "time"
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
 
 type StatusType int
 
@@ -9,25 +18,409 @@ const (
 	Sent
 	Failed
 	Canceled
+	// SentViaFallback — доставлено, но не основным каналом, а одним из резервных
+	SentViaFallback
+)
+
+// ChannelType — имя канала доставки ("email", "sms"). Набор каналов не зашит
+// в модель: его задают отправители, зарегистрированные при старте сервиса.
+type ChannelType string
+
+// Каналы, отправители которых есть в сервисе из коробки
+const (
+	Native   ChannelType = "native"
+	Email    ChannelType = "email"
+	Telegram ChannelType = "telegram"
+	Webhook  ChannelType = "webhook"
 )
 
-type ChannelType int
+// legacyChannels — номера каналов, которыми они задавались до перехода на имена
+var legacyChannels = []ChannelType{Native, Email, Telegram, Webhook}
+
+var channelName = regexp.MustCompile(`^[a-z][a-z0-9_-]*$`)
+
+func (c ChannelType) String() string {
+	return string(c)
+}
+
+// ParseChannel возвращает канал по имени ("telegram") или старому номеру ("2")
+func ParseChannel(s string) (ChannelType, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if n, err := strconv.Atoi(s); err == nil {
+		if n < 0 || n >= len(legacyChannels) {
+			return "", fmt.Errorf("unknown channel %d", n)
+		}
+		return legacyChannels[n], nil
+	}
+	if !channelName.MatchString(s) {
+		return "", fmt.Errorf("invalid channel name %q", s)
+	}
+	return ChannelType(s), nil
+}
+
+// UnmarshalJSON принимает имя канала и, для старых клиентов и сообщений в очереди, его номер
+func (c *ChannelType) UnmarshalJSON(data []byte) error {
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	var s string
+	switch v := v.(type) {
+	case string:
+		s = v
+	case float64:
+		s = strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Errorf("channel must be a name or a number, got %s", data)
+	}
+
+	channel, err := ParseChannel(s)
+	if err != nil {
+		return err
+	}
+	*c = channel
+	return nil
+}
+
+// Priority — срочность уведомления. Более срочные раньше забираются из очереди
+// и не ждут, пока лимиты освободят запас для обычных.
+type Priority int
 
 const (
-	Native ChannelType = iota
-	Email
-	Telegram
+	PriorityLow Priority = iota + 1
+	PriorityNormal
+	PriorityHigh
+	PriorityCritical
 )
 
+// MaxPriority — наибольший приоритет, x-max-priority очереди RabbitMQ
+const MaxPriority = PriorityCritical
+
+var priorityNames = map[Priority]string{
+	PriorityLow:      "low",
+	PriorityNormal:   "normal",
+	PriorityHigh:     "high",
+	PriorityCritical: "critical",
+}
+
+func (p Priority) String() string {
+	if name, ok := priorityNames[p]; ok {
+		return name
+	}
+	return fmt.Sprintf("priority(%d)", int(p))
+}
+
+// Valid сообщает, входит ли приоритет в диапазон от PriorityLow до MaxPriority
+func (p Priority) Valid() bool {
+	return p >= PriorityLow && p <= MaxPriority
+}
+
+// ParsePriority возвращает приоритет по имени ("high") или номеру ("3")
+func ParsePriority(s string) (Priority, error) {
+	for p, name := range priorityNames {
+		if strings.EqualFold(s, name) {
+			return p, nil
+		}
+	}
+	if n, err := strconv.Atoi(s); err == nil && Priority(n).Valid() {
+		return Priority(n), nil
+	}
+	return 0, fmt.Errorf("unknown priority %q", s)
+}
+
 type Notification struct {
-	ID        string      `json:"id" validate:"required"`
-	UserID    string      `json:"user_id" validate:"required"`
+	ID     string `json:"id" validate:"required"`
+	UserID string `json:"user_id" validate:"required"`
+	// Арендатор, от имени ключа которого создано уведомление; задаётся сервером
+	TenantID  string      `json:"tenant_id,omitempty"`
 	Channel   ChannelType `json:"channel" validate:"required"`
 	Recipient string      `json:"recipient" validate:"required"`
 	Message   string      `json:"message" validate:"required"`
 	SendAt    time.Time   `json:"sent_at" validate:"required"`
 	Status    StatusType  `json:"status" validate:"required"`
 	Retry     int         `json:"retry" validate:"required"`
+	// Если не задан, уведомление создаётся с PriorityNormal
+	Priority Priority `json:"priority"`
+	// Version растёт при каждом изменении; копии в очереди со старой версией не отправляются
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// Повторение: cron-выражение или RRULE, ограниченные датой окончания и/или числом срабатываний
+	Schedule       string     `json:"schedule,omitempty"`
+	RepeatUntil    *time.Time `json:"repeat_until,omitempty"`
+	MaxOccurrences int        `json:"max_occurrences,omitempty"`
+	// Серия — ID первого уведомления; каждое срабатывание хранится отдельной строкой
+	SeriesID   string `json:"series_id"`
+	Occurrence int    `json:"occurrence"`
+
+	// Текст ошибки последней неудачной попытки отправки
+	LastError string `json:"last_error,omitempty"`
+
+	// Шаблон: если задан, Message (и Subject, если он пуст) формируются из него перед отправкой
+	Template  string    `json:"template,omitempty"`
+	Locale    string    `json:"locale,omitempty"`
+	Variables Variables `json:"variables,omitempty"`
+	Subject   string    `json:"subject,omitempty"`
+	HTML      bool      `json:"html,omitempty"`
+
+	// Параметры письма для канала email: копии, вложения и текстовая версия HTML
+	Email *EmailOptions `json:"email,omitempty"`
+
+	// Часовой пояс получателя. Если при создании передано LocalSendAt,
+	// SendAt вычисляется из него в этом поясе (или в поясе из профиля пользователя)
+	TimeZone    string `json:"time_zone,omitempty"`
+	LocalSendAt string `json:"local_send_at,omitempty"`
+
+	// Резервные каналы: когда попытки для текущего канала исчерпаны, уведомление
+	// переходит к следующему. Порядок перебора учитывает предпочтения получателя
+	// (TargetOrder); TargetIndex — номер канала в цепочке, 0 — основной канал.
+	Fallbacks        Targets      `json:"fallbacks,omitempty"`
+	TargetIndex      int          `json:"target_index"`
+	DeliveredChannel *ChannelType `json:"delivered_channel,omitempty"`
+
+	// Ключ идемпотентности: повторный запрос с тем же ключом вернёт уже созданное уведомление
+	IdempotencyKey string `json:"idempotency_key,omitempty"`
+
+	// Дайджест, в составе которого уведомление отправлено вместе с другими
+	DigestID string `json:"digest_id,omitempty"`
+}
+
+// Target — канал и получатель, которым можно доставить уведомление
+type Target struct {
+	Channel   ChannelType `json:"channel"`
+	Recipient string      `json:"recipient"`
+}
+
+// NotificationPatch — изменяемые поля запланированного уведомления
+type NotificationPatch struct {
+	SendAt    *time.Time `json:"sent_at"`
+	Message   *string    `json:"message"`
+	Recipient *string    `json:"recipient"`
+}
+
+// IsRecurring сообщает, задано ли у уведомления правило повторения
+func (n *Notification) IsRecurring() bool {
+	return n.Schedule != ""
+}
+
+// Targets возвращает основной канал и все резервные по порядку
+func (n *Notification) Targets() []Target {
+	return append([]Target{{Channel: n.Channel, Recipient: n.Recipient}}, n.Fallbacks...)
+}
+
+// TargetOrder возвращает номера каналов цепочки в порядке перебора: сначала каналы
+// из preferred, затем остальные в исходном порядке. Сама цепочка не меняется, поэтому
+// TargetIndex всегда указывает на канал в сохранённом порядке.
+func (n *Notification) TargetOrder(preferred []ChannelType) []int {
+	order := make([]int, len(n.Fallbacks)+1)
+	for i := range order {
+		order[i] = i
+	}
+	if len(n.Fallbacks) == 0 || len(preferred) == 0 {
+		return order
+	}
+
+	targets := n.Targets()
+	rank := func(i int) int {
+		for r, p := range preferred {
+			if p == targets[i].Channel {
+				return r
+			}
+		}
+		return len(preferred)
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return rank(order[i]) < rank(order[j])
+	})
+	return order
+}
+
+// NextTarget возвращает канал, следующий за текущим в порядке перебора,
+// и false, если текущий канал последний
+func (n *Notification) NextTarget(preferred []ChannelType) (int, bool) {
+	order := n.TargetOrder(preferred)
+	for pos, i := range order {
+		if i == n.TargetIndex && pos+1 < len(order) {
+			return order[pos+1], true
+		}
+	}
+	return 0, false
+}
+
+// IsFallback сообщает, что target — не основной канал, который запросил создатель уведомления
+func (n *Notification) IsFallback(target Notification) bool {
+	return target.Channel != n.Channel || target.Recipient != n.Recipient
+}
+
+// ForTarget возвращает копию уведомления, адресованную i-му каналу цепочки
+func (n *Notification) ForTarget(i int) Notification {
+	c := *n
+	if i > 0 && i <= len(n.Fallbacks) {
+		c.Channel = n.Fallbacks[i-1].Channel
+		c.Recipient = n.Fallbacks[i-1].Recipient
+	}
+	return c
+}
+
+// Targets — резервные каналы уведомления, хранятся в JSONB
+type Targets []Target
+
+func (t Targets) Value() (driver.Value, error) {
+	if t == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal(t)
+}
+
+func (t *Targets) Scan(src any) error {
+	var data []byte
+	switch src := src.(type) {
+	case nil:
+		*t = nil
+		return nil
+	case []byte:
+		data = src
+	case string:
+		data = []byte(src)
+	default:
+		return fmt.Errorf("cannot scan %T into Targets", src)
+	}
+	if err := json.Unmarshal(data, t); err != nil {
+		return err
+	}
+	if len(*t) == 0 {
+		*t = nil
+	}
+	return nil
+}
+
+// EmailOptions — дополнительные параметры письма, хранятся в JSONB
+type EmailOptions struct {
+	CC  []string `json:"cc,omitempty"`
+	BCC []string `json:"bcc,omitempty"`
+	// Text — текстовая альтернатива HTML-письма; если пуста, получается из HTML
+	Text        string       `json:"text,omitempty"`
+	Attachments []Attachment `json:"attachments,omitempty"`
+}
+
+// Attachment — вложение письма: либо ссылка URL на разрешённый в настройках хост,
+// которая скачивается при отправке, либо содержимое Content, переданное в запросе (в JSON — base64)
+type Attachment struct {
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type,omitempty"`
+	URL         string `json:"url,omitempty"`
+	Content     []byte `json:"content,omitempty"`
+	// Inline — встроить в письмо (картинка для <img src="cid:filename">)
+	Inline bool `json:"inline,omitempty"`
+}
+
+func (e EmailOptions) Value() (driver.Value, error) {
+	return json.Marshal(e)
+}
+
+func (e *EmailOptions) Scan(src any) error {
+	var data []byte
+	switch src := src.(type) {
+	case nil:
+		*e = EmailOptions{}
+		return nil
+	case []byte:
+		data = src
+	case string:
+		data = []byte(src)
+	default:
+		return fmt.Errorf("cannot scan %T into EmailOptions", src)
+	}
+	return json.Unmarshal(data, e)
+}
+
+// Variables — значения переменных шаблона, хранятся в JSONB
+type Variables map[string]any
+
+func (v Variables) Value() (driver.Value, error) {
+	if v == nil {
+		return []byte("{}"), nil
+	}
+	return json.Marshal(v)
+}
+
+func (v *Variables) Scan(src any) error {
+	var data []byte
+	switch src := src.(type) {
+	case nil:
+		*v = nil
+		return nil
+	case []byte:
+		data = src
+	case string:
+		data = []byte(src)
+	default:
+		return fmt.Errorf("cannot scan %T into Variables", src)
+	}
+	return json.Unmarshal(data, v)
+}
+
+// Template — именованный шаблон сообщения для канала и языка.
+// Шаблон с пустым Locale используется, если нет варианта для нужного языка.
+type Template struct {
+	ID        string      `json:"id"`
+	Name      string      `json:"name" binding:"required"`
+	Channel   ChannelType `json:"channel"`
+	Locale    string      `json:"locale"`
+	Subject   string      `json:"subject"`
+	Body      string      `json:"body" binding:"required"`
+	HTML      bool        `json:"html"`
 	CreatedAt time.Time   `json:"created_at"`
 	UpdatedAt time.Time   `json:"updated_at"`
 }
+
+// Attempt — одна попытка отправки уведомления
+type Attempt struct {
+	ID             string      `json:"id"`
+	NotificationID string      `json:"notification_id"`
+	Channel        ChannelType `json:"channel"`
+	AttemptedAt    time.Time   `json:"attempted_at"`
+	DurationMs     int64       `json:"duration_ms"`
+	Error          string      `json:"error,omitempty"`
+}
+
+// DeadLetter — уведомление, которое не удалось доставить
+type DeadLetter struct {
+	ID             string    `json:"id"`
+	NotificationID *string   `json:"notification_id"`
+	Reason         string    `json:"reason"`
+	Payload        string    `json:"payload"`
+	FailedAt       time.Time `json:"failed_at"`
+}
+
+// OutboxMessage — сообщение для очереди, записанное в одной транзакции с уведомлением
+type OutboxMessage struct {
+	ID             string     `json:"id"`
+	NotificationID string     `json:"notification_id"`
+	Payload        string     `json:"payload"`
+	PublishAt      time.Time  `json:"publish_at"`
+	Attempts       int        `json:"attempts"`
+	LastError      string     `json:"last_error,omitempty"`
+	NextAttemptAt  time.Time  `json:"next_attempt_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+	PublishedAt    *time.Time `json:"published_at,omitempty"`
+}
+
+// OutboxStats — размер очереди неопубликованных сообщений
+type OutboxStats struct {
+	Pending  int        `json:"pending"`
+	Retrying int        `json:"retrying"`
+	Oldest   *time.Time `json:"oldest,omitempty"`
+}
+
+// TelegramChat — пользователь, зарегистрировавшийся в боте
+type TelegramChat struct {
+	ChatID       int64     `json:"chat_id"`
+	Username     string    `json:"username"`
+	FirstName    string    `json:"first_name"`
+	Active       bool      `json:"active"`
+	RegisteredAt time.Time `json:"registered_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
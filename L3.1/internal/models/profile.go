@@ -0,0 +1,106 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// UserProfile — настройки доставки пользователя
+type UserProfile struct {
+	// Арендатор, которому принадлежит пользователь; задаётся сервером
+	TenantID string `json:"tenant_id,omitempty"`
+	UserID   string `json:"user_id"`
+	TimeZone string `json:"time_zone"`
+	// Тихие часы в часовом поясе пользователя, формат "HH:MM"; окно может переходить через полночь
+	QuietStart string `json:"quiet_start"`
+	QuietEnd   string `json:"quiet_end"`
+	// Каналы в порядке предпочтения: цепочка уведомления с резервными каналами
+	// перебирается начиная с них
+	PreferredChannels []ChannelType `json:"preferred_channels"`
+	// Окно дайджеста ("15m"): уведомления одному получателю, запланированные
+	// в пределах окна, отправляются одним сообщением. Пусто — дайджест выключен.
+	DigestWindow string    `json:"digest_window,omitempty"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+const clockLayout = "15:04"
+
+// Location возвращает часовой пояс пользователя (UTC, если не задан)
+func (p *UserProfile) Location() (*time.Location, error) {
+	if p.TimeZone == "" {
+		return time.UTC, nil
+	}
+	return time.LoadLocation(p.TimeZone)
+}
+
+// Digest возвращает окно дайджеста; 0 — дайджест выключен
+func (p *UserProfile) Digest() time.Duration {
+	window, err := time.ParseDuration(p.DigestWindow)
+	if err != nil || window < 0 {
+		return 0
+	}
+	return window
+}
+
+// Validate проверяет часовой пояс, формат тихих часов и окно дайджеста
+func (p *UserProfile) Validate() error {
+	if _, err := p.Location(); err != nil {
+		return fmt.Errorf("invalid time_zone %q", p.TimeZone)
+	}
+	if (p.QuietStart == "") != (p.QuietEnd == "") {
+		return fmt.Errorf("quiet_start and quiet_end must be set together")
+	}
+	for _, v := range []string{p.QuietStart, p.QuietEnd} {
+		if v == "" {
+			continue
+		}
+		if _, err := time.Parse(clockLayout, v); err != nil {
+			return fmt.Errorf("invalid quiet hours %q: expected HH:MM", v)
+		}
+	}
+	if p.DigestWindow != "" {
+		if window, err := time.ParseDuration(p.DigestWindow); err != nil || window < 0 {
+			return fmt.Errorf("invalid digest_window %q: expected a duration like 15m", p.DigestWindow)
+		}
+	}
+	return nil
+}
+
+// QuietUntil сообщает, попадает ли t в тихие часы, и если да — когда они закончатся
+func (p *UserProfile) QuietUntil(t time.Time) (time.Time, bool) {
+	if p.QuietStart == "" || p.QuietStart == p.QuietEnd {
+		return time.Time{}, false
+	}
+
+	loc, err := p.Location()
+	if err != nil {
+		return time.Time{}, false
+	}
+	start, err1 := time.Parse(clockLayout, p.QuietStart)
+	end, err2 := time.Parse(clockLayout, p.QuietEnd)
+	if err1 != nil || err2 != nil {
+		return time.Time{}, false
+	}
+
+	local := t.In(loc)
+	minute := local.Hour()*60 + local.Minute()
+	startMin := start.Hour()*60 + start.Minute()
+	endMin := end.Hour()*60 + end.Minute()
+
+	var quiet bool
+	if startMin < endMin {
+		quiet = minute >= startMin && minute < endMin
+	} else {
+		// Окно через полночь, например 22:00–08:00
+		quiet = minute >= startMin || minute < endMin
+	}
+	if !quiet {
+		return time.Time{}, false
+	}
+
+	until := time.Date(local.Year(), local.Month(), local.Day(), end.Hour(), end.Minute(), 0, 0, loc)
+	if !until.After(local) {
+		until = until.AddDate(0, 0, 1)
+	}
+	return until, true
+}
@@ -0,0 +1,209 @@
+package queue
+
+import (
+	"container/heap"
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"delayed-notifier/internal/models"
+)
+
+// MemoryScheduler хранит отложенные сообщения в памяти процесса: куча по времени
+// доставки и один таймер на ближайшее сообщение. Наступившие сообщения перекладываются
+// в кучу по приоритету, как в приоритетной очереди RabbitMQ. При перезапуске
+// сообщения теряются, поэтому планировщик подходит для локального запуска и тестов.
+type MemoryScheduler struct {
+	workers int
+
+	lock    sync.Mutex
+	pending delayedHeap
+	ready   readyHeap
+	wake    chan struct{}
+
+	due      chan delayed
+	stopChan chan struct{}
+	wg       sync.WaitGroup
+}
+
+var _ Scheduler = (*MemoryScheduler)(nil)
+
+func NewMemoryScheduler(workers int) *MemoryScheduler {
+	if workers <= 0 {
+		workers = 1
+	}
+
+	return &MemoryScheduler{
+		workers:  workers,
+		wake:     make(chan struct{}, 1),
+		due:      make(chan delayed),
+		stopChan: make(chan struct{}),
+	}
+}
+
+// Publish кладёт сообщение в кучу и будит диспетчер, если оно стало ближайшим
+func (m *MemoryScheduler) Publish(body []byte, sendAt time.Time) error {
+	m.push(delayed{at: sendAt, body: body, priority: priorityOf(body)})
+	return nil
+}
+
+func (m *MemoryScheduler) push(d delayed) {
+	m.lock.Lock()
+	heap.Push(&m.pending, d)
+	m.lock.Unlock()
+
+	select {
+	case m.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Consume запускает диспетчер и воркеров
+func (m *MemoryScheduler) Consume(handler Handler) error {
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		m.dispatch()
+	}()
+
+	for i := 0; i < m.workers; i++ {
+		m.wg.Add(1)
+		go func() {
+			defer m.wg.Done()
+			for {
+				select {
+				case <-m.stopChan:
+					return
+				case d := <-m.due:
+					m.handle(handler, d)
+				}
+			}
+		}()
+	}
+
+	return nil
+}
+
+// dispatch передаёт воркерам наступившие сообщения, начиная с самых срочных, и спит до следующего
+func (m *MemoryScheduler) dispatch() {
+	timer := time.NewTimer(time.Hour)
+	defer timer.Stop()
+
+	for {
+		m.lock.Lock()
+		var (
+			next  delayed
+			ready bool
+			wait  = time.Hour
+		)
+		for m.pending.Len() > 0 {
+			if wait = time.Until(m.pending[0].at); wait > 0 {
+				break
+			}
+			heap.Push(&m.ready, heap.Pop(&m.pending))
+		}
+		if m.ready.Len() > 0 {
+			next = heap.Pop(&m.ready).(delayed)
+			ready = true
+		}
+		m.lock.Unlock()
+
+		if ready {
+			select {
+			case m.due <- next:
+			case <-m.stopChan:
+				return
+			}
+			continue
+		}
+
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(wait)
+
+		select {
+		case <-timer.C:
+		case <-m.wake:
+		case <-m.stopChan:
+			log.Println("Memory scheduler stopping...")
+			return
+		}
+	}
+}
+
+// handle повторяет поведение RabbitMQ-очереди: при первой ошибке обработчика
+// сообщение возвращается в очередь, при повторной — отбрасывается
+func (m *MemoryScheduler) handle(handler Handler, d delayed) {
+	var notification models.Notification
+	if err := json.Unmarshal(d.body, &notification); err != nil {
+		log.Println("Failed to parse notification:", err)
+		return
+	}
+
+	if err := handler(context.Background(), notification); err != nil {
+		log.Println("handler failed:", err)
+		if d.redelivered {
+			log.Printf("notification %s dropped after redelivery", notification.ID)
+			return
+		}
+		d.redelivered = true
+		d.at = time.Now()
+		m.push(d)
+	}
+}
+
+// Close останавливает диспетчер и воркеров; неотправленные сообщения теряются
+func (m *MemoryScheduler) Close() error {
+	close(m.stopChan)
+	m.wg.Wait()
+	return nil
+}
+
+// Len возвращает число ожидающих сообщений
+func (m *MemoryScheduler) Len() int {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.pending.Len() + m.ready.Len()
+}
+
+type delayed struct {
+	at          time.Time
+	body        []byte
+	priority    models.Priority
+	redelivered bool
+}
+
+// delayedHeap — min-куча по времени доставки для container/heap
+type delayedHeap []delayed
+
+func (h delayedHeap) Len() int           { return len(h) }
+func (h delayedHeap) Less(i, j int) bool { return h[i].at.Before(h[j].at) }
+func (h delayedHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+func (h *delayedHeap) Push(x any) {
+	*h = append(*h, x.(delayed))
+}
+
+func (h *delayedHeap) Pop() any {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[:n-1]
+	return x
+}
+
+// readyHeap — куча наступивших сообщений: сначала больший приоритет, затем более раннее время
+type readyHeap struct{ delayedHeap }
+
+func (h readyHeap) Less(i, j int) bool {
+	if h.delayedHeap[i].priority != h.delayedHeap[j].priority {
+		return h.delayedHeap[i].priority > h.delayedHeap[j].priority
+	}
+	return h.delayedHeap.Less(i, j)
+}
@@ -0,0 +1,172 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"delayed-notifier/internal/models"
+)
+
+// DueStore — хранилище, из которого PostgresScheduler забирает наступившие уведомления
+type DueStore interface {
+	// ClaimDue атомарно захватывает до limit наступивших уведомлений на время lease
+	ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]*models.Notification, error)
+	// Reschedule переносит момент, когда уведомление снова станет доступно для захвата
+	Reschedule(ctx context.Context, id int, at time.Time) error
+}
+
+// PostgresSchedulerConfig задаёт параметры опроса
+type PostgresSchedulerConfig struct {
+	PollInterval time.Duration
+	BatchSize    int
+	// Workers — число параллельных обработчиков одной пачки
+	Workers int
+	// Lease — на сколько захваченное уведомление скрывается от других реплик;
+	// если обработчик не завершился за это время, уведомление будет захвачено повторно
+	Lease time.Duration
+}
+
+// PostgresScheduler доставляет уведомления, опрашивая таблицу notifications.
+// Захват строк идёт через SELECT ... FOR UPDATE SKIP LOCKED, поэтому
+// несколько реплик могут работать одновременно без двойной отправки.
+type PostgresScheduler struct {
+	store    DueStore
+	cfg      PostgresSchedulerConfig
+	stopChan chan struct{}
+	wg       sync.WaitGroup
+}
+
+var _ Scheduler = (*PostgresScheduler)(nil)
+
+func NewPostgresScheduler(store DueStore, cfg PostgresSchedulerConfig) *PostgresScheduler {
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = time.Second
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 10
+	}
+	if cfg.Workers <= 0 {
+		cfg.Workers = 1
+	}
+	if cfg.Lease <= 0 {
+		cfg.Lease = 5 * time.Minute
+	}
+
+	return &PostgresScheduler{
+		store:    store,
+		cfg:      cfg,
+		stopChan: make(chan struct{}),
+	}
+}
+
+// Publish запоминает время доставки: строка уже лежит в notifications,
+// достаточно сделать её видимой для опроса начиная с sendAt
+func (p *PostgresScheduler) Publish(body []byte, sendAt time.Time) error {
+	var notif models.Notification
+	if err := json.Unmarshal(body, &notif); err != nil {
+		return fmt.Errorf("failed to parse notification: %w", err)
+	}
+
+	id, err := strconv.Atoi(notif.ID)
+	if err != nil {
+		return fmt.Errorf("invalid notification id %q: %w", notif.ID, err)
+	}
+
+	if err := p.store.Reschedule(context.Background(), id, sendAt); err != nil {
+		log.Printf("Publish error: %v", err)
+		return err
+	}
+
+	log.Printf("Notification %d scheduled for polling at %s", id, sendAt.Format(time.RFC3339))
+	return nil
+}
+
+// Consume запускает цикл опроса
+func (p *PostgresScheduler) Consume(handler Handler) error {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		defer cancel()
+
+		ticker := time.NewTicker(p.cfg.PollInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-p.stopChan:
+				log.Println("Postgres scheduler stopping...")
+				return
+			case <-ticker.C:
+				p.poll(ctx, handler)
+			}
+		}
+	}()
+
+	return nil
+}
+
+func (p *PostgresScheduler) poll(ctx context.Context, handler Handler) {
+	for {
+		due, err := p.store.ClaimDue(ctx, p.cfg.BatchSize, p.cfg.Lease)
+		if err != nil {
+			log.Println("Failed to claim due notifications:", err)
+			return
+		}
+
+		p.handleBatch(ctx, handler, due)
+
+		// Неполная пачка — наступивших уведомлений больше нет
+		if len(due) < p.cfg.BatchSize {
+			return
+		}
+
+		select {
+		case <-p.stopChan:
+			return
+		default:
+		}
+	}
+}
+
+// handleBatch раздаёт пачку воркерам. Если обработчик вернул ошибку,
+// уведомление снова станет доступно после истечения lease.
+func (p *PostgresScheduler) handleBatch(ctx context.Context, handler Handler, due []*models.Notification) {
+	jobs := make(chan *models.Notification)
+
+	var wg sync.WaitGroup
+	for i := 0; i < p.cfg.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for notif := range jobs {
+				if err := handler(ctx, *notif); err != nil {
+					log.Println("handler failed:", err)
+				}
+			}
+		}()
+	}
+
+	for _, notif := range due {
+		jobs <- notif
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// Close останавливает опрос и дожидается текущей пачки
+func (p *PostgresScheduler) Close() error {
+	log.Println("Closing postgres scheduler...")
+
+	close(p.stopChan)
+	p.wg.Wait()
+
+	log.Println("Postgres scheduler closed.")
+	return nil
+}
@@ -3,6 +3,7 @@ package queue
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"sync"
@@ -14,17 +15,38 @@ import (
 	"github.com/wb-go/wbf/rabbitmq"
 )
 
+// Основная очередь с x-max-priority. Аргументы существующей очереди поменять
+// нельзя, поэтому приоритетная очередь объявляется под новым именем, а очередь
+// прежних версий отвязывается от exchange и дочитывается.
+const (
+	mainQueue       = "notifications.main.v2"
+	legacyMainQueue = "notifications.main"
+)
+
 type Queue struct {
 	conn      *rabbitmq.Connection
 	channel   *rabbitmq.Channel
 	publisher *rabbitmq.Publisher
 	consumer  *rabbitmq.Consumer
 	stopChan  chan struct{}
-	handler   func(context.Context, models.Notification) error
+	handler   Handler
+	workers   int
 	wg        sync.WaitGroup
+	// drainLegacy — в очереди прежних версий остались сообщения
+	drainLegacy bool
 }
 
-func NewQueue(url string) (*Queue, error) {
+var (
+	_ Scheduler        = (*Queue)(nil)
+	_ DeadLetterSource = (*Queue)(nil)
+)
+
+// NewQueue подключается к RabbitMQ; workers — число параллельных обработчиков основной очереди
+func NewQueue(url string, workers int) (*Queue, error) {
+	if workers <= 0 {
+		workers = 1
+	}
+
 	conn, err := rabbitmq.Connect(url, 3, 5*time.Second)
 	if err != nil {
 		return nil, err
@@ -40,6 +62,7 @@ func NewQueue(url string) (*Queue, error) {
 		conn:     conn,
 		channel:  ch,
 		stopChan: make(chan struct{}),
+		workers:  workers,
 	}
 
 	// Настраиваем очереди
@@ -50,14 +73,26 @@ func NewQueue(url string) (*Queue, error) {
 
 	// Создаем publisher и consumer
 	q.publisher = rabbitmq.NewPublisher(ch, "")
-	q.consumer = rabbitmq.NewConsumer(ch, rabbitmq.NewConsumerConfig("notifications.main"))
+	q.consumer = rabbitmq.NewConsumer(ch, rabbitmq.NewConsumerConfig(mainQueue))
 
 	return q, nil
 }
 
-// Consume читает сообщения из очереди.
-func (q *Queue) Consume(msgChan chan []byte) error {
-	return q.consumer.Consume(msgChan)
+// Ping проверяет, что соединение и канал с RabbitMQ открыты
+func (q *Queue) Ping() error {
+	if q.conn.IsClosed() {
+		return errors.New("rabbitmq connection is closed")
+	}
+	if q.channel.IsClosed() {
+		return errors.New("rabbitmq channel is closed")
+	}
+	return nil
+}
+
+// Consume назначает обработчик и запускает чтение основной очереди
+func (q *Queue) Consume(handler Handler) error {
+	q.SetHandler(handler)
+	return q.StartMainConsumer()
 }
 
 func (q *Queue) SetupQueues() error {
@@ -89,13 +124,15 @@ func (q *Queue) SetupQueues() error {
 		return err
 	}
 
-	// Создаём main очередь с DLQ
+	// Создаём main очередь с DLQ. Приоритетная очередь отдаёт готовые
+	// сообщения с большим приоритетом раньше остальных.
 	mainQueueArgs := amqp091.Table{
 		"x-dead-letter-exchange":    "",
 		"x-dead-letter-routing-key": "notifications.dlq",
+		"x-max-priority":            int32(models.MaxPriority),
 	}
 	if _, err := q.channel.QueueDeclare(
-		"notifications.main",
+		mainQueue,
 		true,
 		false,
 		false,
@@ -105,21 +142,65 @@ func (q *Queue) SetupQueues() error {
 		return err
 	}
 
-	// Привязываем main очередь к delayed exchange
-	if err := q.channel.QueueBind(
-		"notifications.main",    // queue
-		"notifications.main",    // routing key
-		"notifications.delayed", // exchange
-		false,
-		nil,
-	); err != nil {
+	// Привязываем main очередь к delayed exchange. Сообщения, которые прежние
+	// версии отложили в exchange с ключом notifications.main, тоже придут сюда.
+	for _, key := range []string{mainQueue, legacyMainQueue} {
+		if err := q.channel.QueueBind(
+			mainQueue,               // queue
+			key,                     // routing key
+			"notifications.delayed", // exchange
+			false,
+			nil,
+		); err != nil {
+			return err
+		}
+	}
+
+	drain, err := q.detachLegacyQueue()
+	if err != nil {
 		return err
 	}
+	q.drainLegacy = drain
 
 	return nil
 }
 
-// Публикация
+// detachLegacyQueue отвязывает очередь прежних версий от exchange, чтобы сообщения
+// не дублировались, и удаляет её, если она пуста. Возвращает true, если в очереди
+// остались сообщения. Ошибка AMQP закрывает канал, поэтому работаем в отдельном.
+func (q *Queue) detachLegacyQueue() (bool, error) {
+	ch, err := q.conn.Channel()
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = ch.Close() }()
+
+	legacy, err := ch.QueueDeclarePassive(legacyMainQueue, true, false, false, false, nil)
+	if err != nil {
+		var amqpErr *amqp091.Error
+		if errors.As(err, &amqpErr) && amqpErr.Code == amqp091.NotFound {
+			return false, nil
+		}
+		return false, err
+	}
+
+	if err := ch.QueueUnbind(legacyMainQueue, legacyMainQueue, "notifications.delayed", nil); err != nil {
+		return false, err
+	}
+
+	// Очередь ещё читают экземпляры прежней версии или в ней есть сообщения — дочитываем
+	if legacy.Consumers == 0 && legacy.Messages == 0 {
+		if _, err := ch.QueueDelete(legacyMainQueue, false, true, false); err == nil {
+			log.Printf("Legacy queue %s is empty and was deleted", legacyMainQueue)
+			return false, nil
+		}
+	}
+
+	log.Printf("Draining %d messages from legacy queue %s", legacy.Messages, legacyMainQueue)
+	return true, nil
+}
+
+// Публикация. Приоритет сообщения берётся из уведомления в body.
 func (q *Queue) Publish(body []byte, sendAt time.Time) error {
 	delay := time.Until(sendAt)
 	if delay < 0 {
@@ -128,11 +209,12 @@ func (q *Queue) Publish(body []byte, sendAt time.Time) error {
 
 	err := q.channel.Publish(
 		"notifications.delayed", // exchange
-		"notifications.main",    // routing key
+		mainQueue,               // routing key
 		false,                   // mandatory
 		false,                   // immediate
 		amqp091.Publishing{
 			ContentType: "text/plain",
+			Priority:    uint8(priorityOf(body)),
 			Body:        body,
 			Headers: amqp091.Table{
 				"x-delay": int64(delay.Milliseconds()),
@@ -149,12 +231,13 @@ func (q *Queue) Publish(body []byte, sendAt time.Time) error {
 	return err
 }
 
-// Запуск DLQ Consumer
-func (q *Queue) StartDLQConsumer() {
+// Запуск DLQ Consumer. Сообщение подтверждается только после того,
+// как handler сохранил его; иначе оно остаётся в notifications.dlq.
+func (q *Queue) StartDLQConsumer(handler DeadLetterHandler) error {
 	msgs, err := q.channel.Consume(
 		"notifications.dlq",
 		"",
-		true,  // autoAck
+		false, // autoAck
 		false, // exclusive
 		false, // noLocal
 		false, // noWait
@@ -162,7 +245,7 @@ func (q *Queue) StartDLQConsumer() {
 	)
 	if err != nil {
 		log.Println("DLQ consume error:", err)
-		return
+		return err
 	}
 
 	q.wg.Add(1)
@@ -179,59 +262,137 @@ func (q *Queue) StartDLQConsumer() {
 					return
 				}
 				log.Printf("DLQ сообщение: %s\n", string(msg.Body))
+
+				if err := handler(context.Background(), msg.Body, deathReason(msg)); err != nil {
+					log.Println("Failed to store dead letter:", err)
+					if err := msg.Nack(false, true); err != nil {
+						log.Println("Nack failed:", err)
+					}
+					continue
+				}
+				if err := msg.Ack(false); err != nil {
+					log.Println("Ack failed:", err)
+				}
 			}
 		}
 	}()
+
+	return nil
 }
 
-// Запуск Main Consumer
-func (q *Queue) StartMainConsumer() {
+// ConsumeDeadLetters реализует DeadLetterSource
+func (q *Queue) ConsumeDeadLetters(handler DeadLetterHandler) error {
+	return q.StartDLQConsumer(handler)
+}
+
+// deathReason достаёт из заголовков RabbitMQ причину, по которой сообщение попало в DLQ
+func deathReason(msg amqp091.Delivery) string {
+	reason, _ := msg.Headers["x-first-death-reason"].(string)
+	queue, _ := msg.Headers["x-first-death-queue"].(string)
+	if reason == "" {
+		return "dead-lettered by broker"
+	}
+	if queue != "" {
+		return fmt.Sprintf("%s in %s", reason, queue)
+	}
+	return reason
+}
+
+// Запуск Main Consumer. Сообщение подтверждается только после того,
+// как обработчик сохранил результат; при сбое обработчика оно возвращается
+// в очередь один раз, а при повторном сбое уходит в DLQ.
+func (q *Queue) StartMainConsumer() error {
+	// Не больше одного неподтверждённого сообщения на воркера
+	if err := q.channel.Qos(q.workers, 0, false); err != nil {
+		return err
+	}
+
 	msgs, err := q.channel.Consume(
-		"notifications.main", // main очередь
-		"",                   // consumer tag
-		true,                 // autoAck
-		false,                // exclusive
-		false,                // noLocal
-		false,                // noWait
-		nil,                  // args
+		mainQueue, // main очередь
+		"",        // consumer tag
+		false,     // autoAck
+		false,     // exclusive
+		false,     // noLocal
+		false,     // noWait
+		nil,       // args
 	)
 	if err != nil {
 		log.Println("Main consume error:", err)
-		return
+		return err
 	}
 
-	q.wg.Add(1)
-	go func() {
-		defer q.wg.Done()
-		for {
-			select {
-			case <-q.stopChan:
-				log.Println("Main consumer stopping...")
-				return
-			case msg, ok := <-msgs:
-				if !ok {
-					log.Println("Main consumer channel closed")
-					return
-				}
-				var notification models.Notification
-				if err := json.Unmarshal(msg.Body, &notification); err != nil {
-					log.Println("Failed to parse notification:", err)
-					continue
-				}
+	// Очередь прежних версий читают те же воркеры; nil-канал в select не срабатывает
+	var legacyMsgs <-chan amqp091.Delivery
+	if q.drainLegacy {
+		legacyMsgs, err = q.channel.Consume(legacyMainQueue, "", false, false, false, false, nil)
+		if err != nil {
+			log.Println("Legacy queue consume error:", err)
+			return err
+		}
+	}
 
-				if q.handler != nil {
-					if err := q.handler(context.Background(), notification); err != nil {
-						log.Println("handler failed:", err)
+	for i := 0; i < q.workers; i++ {
+		q.wg.Add(1)
+		go func(legacyMsgs <-chan amqp091.Delivery) {
+			defer q.wg.Done()
+			for {
+				select {
+				case <-q.stopChan:
+					log.Println("Main consumer stopping...")
+					return
+				case msg, ok := <-msgs:
+					if !ok {
+						log.Println("Main consumer channel closed")
+						return
+					}
+					q.handleDelivery(msg)
+				case msg, ok := <-legacyMsgs:
+					if !ok {
+						legacyMsgs = nil
+						continue
 					}
-				} else {
-					log.Println("no handler set for queue")
+					q.handleDelivery(msg)
 				}
 			}
+		}(legacyMsgs)
+	}
+
+	return nil
+}
+
+func (q *Queue) handleDelivery(msg amqp091.Delivery) {
+	var notification models.Notification
+	if err := json.Unmarshal(msg.Body, &notification); err != nil {
+		log.Println("Failed to parse notification:", err)
+		if err := msg.Nack(false, false); err != nil {
+			log.Println("Nack failed:", err)
 		}
-	}()
+		return
+	}
+
+	if q.handler == nil {
+		log.Println("no handler set for queue")
+		if err := msg.Nack(false, true); err != nil {
+			log.Println("Nack failed:", err)
+		}
+		return
+	}
+
+	if err := q.handler(context.Background(), notification); err != nil {
+		log.Println("handler failed:", err)
+		// Первый сбой — возвращаем в очередь, повторный — в DLQ
+		if err := msg.Nack(false, !msg.Redelivered); err != nil {
+			log.Println("Nack failed:", err)
+		}
+		return
+	}
+
+	if err := msg.Ack(false); err != nil {
+		log.Println("Ack failed:", err)
+	}
 }
 
-func (q *Queue) SetHandler(handler func(context.Context, models.Notification) error) {
+func (q *Queue) SetHandler(handler Handler) {
 	q.handler = handler
 }
 
@@ -0,0 +1,40 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"delayed-notifier/internal/models"
+)
+
+// Handler обрабатывает уведомление, время отправки которого наступило
+type Handler func(context.Context, models.Notification) error
+
+// Scheduler откладывает доставку уведомлений до заданного времени.
+// Реализации: Queue (RabbitMQ с x-delayed-message) и PostgresScheduler (опрос таблицы notifications).
+type Scheduler interface {
+	// Publish планирует доставку сериализованного уведомления на время sendAt
+	Publish(body []byte, sendAt time.Time) error
+	// Consume запускает доставку наступивших уведомлений в handler
+	Consume(handler Handler) error
+	// Close останавливает доставку и освобождает ресурсы
+	Close() error
+}
+
+// DeadLetterHandler сохраняет сообщение, которое брокер не смог доставить
+type DeadLetterHandler func(ctx context.Context, body []byte, reason string) error
+
+// DeadLetterSource реализуют планировщики с собственной очередью недоставленных сообщений
+type DeadLetterSource interface {
+	ConsumeDeadLetters(handler DeadLetterHandler) error
+}
+
+// priorityOf достаёт приоритет из сериализованного уведомления
+func priorityOf(body []byte) models.Priority {
+	var n struct {
+		Priority models.Priority `json:"priority"`
+	}
+	_ = json.Unmarshal(body, &n)
+	return n.Priority
+}
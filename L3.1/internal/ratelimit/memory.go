@@ -0,0 +1,126 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"delayed-notifier/internal/models"
+)
+
+// Backend — общий интерфейс Redis- и in-memory-лимитеров
+type Backend interface {
+	Reserve(ctx context.Context, n models.Notification) (time.Duration, error)
+	Status(ctx context.Context) (map[string]Status, Limit, error)
+}
+
+var (
+	_ Backend = (*Limiter)(nil)
+	_ Backend = (*MemoryLimiter)(nil)
+)
+
+// MemoryLimiter — тот же token bucket, что и Limiter, но в памяти одного процесса
+type MemoryLimiter struct {
+	channels  map[models.ChannelType]Limit
+	recipient Limit
+
+	lock    sync.Mutex
+	buckets map[string]*bucket
+}
+
+type bucket struct {
+	limit  Limit
+	tokens float64
+	ts     time.Time
+}
+
+// После скольких корзин удалять полные, заменяя PEXPIRE из Redis-версии
+const sweepThreshold = 10000
+
+func NewMemoryLimiter(channels map[models.ChannelType]Limit, recipient Limit) *MemoryLimiter {
+	return &MemoryLimiter{
+		channels:  channels,
+		recipient: recipient,
+		buckets:   make(map[string]*bucket),
+	}
+}
+
+// Reserve списывает по токену из корзин канала и получателя, только если в обеих
+// есть нужный приоритету запас
+func (l *MemoryLimiter) Reserve(ctx context.Context, n models.Notification) (time.Duration, error) {
+	type take struct {
+		key   string
+		limit Limit
+	}
+	var takes []take
+	if limit := l.channels[n.Channel]; limit.enabled() {
+		takes = append(takes, take{channelKey(n.Channel), limit})
+	}
+	if l.recipient.enabled() {
+		takes = append(takes, take{recipientKey(n.Channel, n.Recipient), l.recipient})
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	now := time.Now()
+	if len(l.buckets) > sweepThreshold {
+		l.sweep(now)
+	}
+
+	var wait time.Duration
+	for _, t := range takes {
+		b := l.refill(t.key, t.limit, now)
+		if need := t.limit.need(n.Priority); b.tokens < need {
+			wait = max(wait, time.Duration(math.Ceil((need-b.tokens)/t.limit.Rate*1000))*time.Millisecond)
+		}
+	}
+	if wait > 0 {
+		return wait, nil
+	}
+
+	for _, t := range takes {
+		l.buckets[t.key].tokens--
+	}
+	return 0, nil
+}
+
+func (l *MemoryLimiter) refill(key string, limit Limit, now time.Time) *bucket {
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{limit: limit, tokens: float64(limit.Burst), ts: now}
+		l.buckets[key] = b
+	}
+
+	b.tokens = math.Min(float64(limit.Burst), b.tokens+now.Sub(b.ts).Seconds()*limit.Rate)
+	b.ts = now
+	return b
+}
+
+// sweep удаляет корзины, успевшие наполниться: они неотличимы от отсутствующих
+func (l *MemoryLimiter) sweep(now time.Time) {
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.ts).Seconds()*b.limit.Rate >= float64(b.limit.Burst) {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// Status возвращает настроенные лимиты и текущий запас токенов каналов
+func (l *MemoryLimiter) Status(ctx context.Context) (map[string]Status, Limit, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	now := time.Now()
+	channels := make(map[string]Status, len(l.channels))
+	for channel, limit := range l.channels {
+		st := Status{Limit: limit}
+		if _, ok := l.buckets[channelKey(channel)]; ok {
+			level := l.refill(channelKey(channel), limit, now).tokens
+			st.Tokens = &level
+		}
+		channels[channel.String()] = st
+	}
+	return channels, l.recipient, nil
+}
@@ -0,0 +1,200 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"delayed-notifier/internal/models"
+
+	"github.com/wb-go/wbf/redis"
+)
+
+// Limit — параметры token bucket: Rate токенов в секунду, не больше Burst в запасе
+type Limit struct {
+	Rate  float64 `json:"rate"`
+	Burst int     `json:"burst"`
+}
+
+func (l Limit) enabled() bool {
+	return l.Rate > 0 && l.Burst > 0
+}
+
+// reservedShare — доля корзины, которую уведомление с таким приоритетом
+// должно оставить нетронутой. Срочные уведомления тратят запас целиком,
+// поэтому не ждут, пока рассылки пониже приоритетом выберут лимит.
+var reservedShare = map[models.Priority]float64{
+	models.PriorityLow:    0.4,
+	models.PriorityNormal: 0.2,
+}
+
+// need возвращает, сколько токенов должно быть в корзине, чтобы отправить уведомление с приоритетом p
+func (l Limit) need(p models.Priority) float64 {
+	if p == 0 {
+		p = models.PriorityNormal
+	}
+	return 1 + math.Floor(float64(l.Burst)*reservedShare[p])
+}
+
+// ParseLimit разбирает лимит в формате "rate:burst" ("30:60") или "rate" (burst = rate)
+func ParseLimit(s string) (Limit, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return Limit{}, nil
+	}
+
+	rateStr, burstStr, hasBurst := strings.Cut(s, ":")
+	rate, err := strconv.ParseFloat(rateStr, 64)
+	if err != nil || rate < 0 {
+		return Limit{}, fmt.Errorf("invalid rate limit %q", s)
+	}
+
+	burst := int(rate)
+	if hasBurst {
+		if burst, err = strconv.Atoi(burstStr); err != nil || burst < 0 {
+			return Limit{}, fmt.Errorf("invalid rate limit %q", s)
+		}
+	}
+	if burst < 1 && rate > 0 {
+		burst = 1
+	}
+
+	return Limit{Rate: rate, Burst: burst}, nil
+}
+
+// ParseChannelLimits разбирает список вида "telegram=30:30,email=10"
+func ParseChannelLimits(s string) (map[models.ChannelType]Limit, error) {
+	limits := make(map[models.ChannelType]Limit)
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		name, value, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid channel limit %q: expected channel=rate[:burst]", item)
+		}
+		channel, err := models.ParseChannel(strings.TrimSpace(name))
+		if err != nil {
+			return nil, err
+		}
+		limit, err := ParseLimit(value)
+		if err != nil {
+			return nil, err
+		}
+		limits[channel] = limit
+	}
+	return limits, nil
+}
+
+// Status — лимит и текущий запас токенов
+type Status struct {
+	Limit
+	Tokens *float64 `json:"tokens,omitempty"`
+}
+
+// Limiter — распределённый token bucket в Redis, общий для всех реплик
+type Limiter struct {
+	client    *redis.Client
+	channels  map[models.ChannelType]Limit
+	recipient Limit
+}
+
+func NewLimiter(client *redis.Client, channels map[models.ChannelType]Limit, recipient Limit) *Limiter {
+	return &Limiter{
+		client:    client,
+		channels:  channels,
+		recipient: recipient,
+	}
+}
+
+// takeScript атомарно списывает по токену из всех переданных корзин.
+// Если хотя бы в одной меньше need токенов, ничего не списывается и возвращается
+// время ожидания в миллисекундах.
+var takeScript = `
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+local wait = 0
+local tokens = {}
+for i, key in ipairs(KEYS) do
+	local rate = tonumber(ARGV[3 * i - 2])
+	local burst = tonumber(ARGV[3 * i - 1])
+	local need = tonumber(ARGV[3 * i])
+	local state = redis.call('HMGET', key, 'tokens', 'ts')
+	local level = tonumber(state[1]) or burst
+	local ts = tonumber(state[2]) or now
+	level = math.min(burst, level + math.max(0, now - ts) / 1000 * rate)
+	tokens[i] = level
+	if level < need then
+		wait = math.max(wait, math.ceil((need - level) / rate * 1000))
+	end
+end
+for i, key in ipairs(KEYS) do
+	local rate = tonumber(ARGV[3 * i - 2])
+	local burst = tonumber(ARGV[3 * i - 1])
+	if wait == 0 then
+		tokens[i] = tokens[i] - 1
+	end
+	redis.call('HSET', key, 'tokens', tostring(tokens[i]), 'ts', now)
+	redis.call('PEXPIRE', key, math.ceil(burst / rate * 1000) + 1000)
+end
+return wait
+`
+
+// Reserve берёт токен для канала и получателя уведомления; менее срочным
+// уведомлениям нужен больший запас в корзинах.
+// Ненулевой результат означает, что отправку нужно отложить на это время.
+func (l *Limiter) Reserve(ctx context.Context, n models.Notification) (time.Duration, error) {
+	var (
+		keys []string
+		args []any
+	)
+	if limit := l.channels[n.Channel]; limit.enabled() {
+		keys = append(keys, channelKey(n.Channel))
+		args = append(args, limit.Rate, limit.Burst, limit.need(n.Priority))
+	}
+	if l.recipient.enabled() {
+		keys = append(keys, recipientKey(n.Channel, n.Recipient))
+		args = append(args, l.recipient.Rate, l.recipient.Burst, l.recipient.need(n.Priority))
+	}
+	if len(keys) == 0 {
+		return 0, nil
+	}
+
+	wait, err := l.client.Eval(ctx, takeScript, keys, args...).Int64()
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(wait) * time.Millisecond, nil
+}
+
+// Status возвращает настроенные лимиты и текущий запас токенов каналов
+func (l *Limiter) Status(ctx context.Context) (map[string]Status, Limit, error) {
+	channels := make(map[string]Status, len(l.channels))
+	for channel, limit := range l.channels {
+		st := Status{Limit: limit}
+
+		level, err := l.client.HGet(ctx, channelKey(channel), "tokens").Float64()
+		switch {
+		case err == nil:
+			st.Tokens = &level
+		case err != redis.NoMatches:
+			return nil, Limit{}, err
+		}
+
+		channels[channel.String()] = st
+	}
+	return channels, l.recipient, nil
+}
+
+func channelKey(channel models.ChannelType) string {
+	return "ratelimit:channel:" + channel.String()
+}
+
+func recipientKey(channel models.ChannelType, recipient string) string {
+	return "ratelimit:recipient:" + channel.String() + ":" + recipient
+}
@@ -0,0 +1,77 @@
+package recurrence
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"github.com/teambition/rrule-go"
+)
+
+// Rule вычисляет время следующего срабатывания повторяющегося уведомления
+type Rule interface {
+	// Next возвращает первое срабатывание строго после after.
+	// Нулевое время означает, что срабатываний больше нет.
+	Next(after time.Time) time.Time
+}
+
+var cronParser = cron.NewParser(
+	cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor,
+)
+
+// Parse разбирает правило повторения: cron-выражение ("0 9 * * 1-5", "@daily")
+// или iCal RRULE ("FREQ=DAILY;BYHOUR=9" или "RRULE:FREQ=WEEKLY").
+// start — время первого срабатывания серии, от него отсчитывается RRULE.
+func Parse(expr string, start time.Time) (Rule, error) {
+	expr = strings.TrimSpace(expr)
+	if expr == "" {
+		return nil, errors.New("empty recurrence rule")
+	}
+
+	if isRRule(expr) {
+		if strings.EqualFold(expr[:min(len(expr), 6)], "RRULE:") {
+			expr = expr[6:]
+		}
+		opt, err := rrule.StrToROption(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rrule %q: %w", expr, err)
+		}
+		if opt.Dtstart.IsZero() {
+			opt.Dtstart = start
+		}
+		r, err := rrule.NewRRule(*opt)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rrule %q: %w", expr, err)
+		}
+		return &rruleRule{r: r}, nil
+	}
+
+	sched, err := cronParser.Parse(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cron expression %q: %w", expr, err)
+	}
+	return &cronRule{s: sched}, nil
+}
+
+func isRRule(expr string) bool {
+	upper := strings.ToUpper(expr)
+	return strings.HasPrefix(upper, "RRULE:") || strings.Contains(upper, "FREQ=")
+}
+
+type cronRule struct {
+	s cron.Schedule
+}
+
+func (c *cronRule) Next(after time.Time) time.Time {
+	return c.s.Next(after)
+}
+
+type rruleRule struct {
+	r *rrule.RRule
+}
+
+func (r *rruleRule) Next(after time.Time) time.Time {
+	return r.r.After(after, false)
+}
@@ -0,0 +1,15 @@
+package repository
+
+import (
+	"context"
+
+	"delayed-notifier/internal/models"
+)
+
+type APIKeyRepo interface {
+	Create(ctx context.Context, k *models.APIKey, hash string) error
+	GetByHash(ctx context.Context, hash string) (*models.APIKey, error)
+	List(ctx context.Context, tenantID string) ([]*models.APIKey, error)
+	Revoke(ctx context.Context, id int) error
+	Touch(ctx context.Context, id string) error
+}
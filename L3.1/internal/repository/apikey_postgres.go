@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"delayed-notifier/internal/models"
+
+	"github.com/wb-go/wbf/dbpg"
+)
+
+const apiKeyColumns = `id, tenant_id, name, prefix, scopes, created_at, last_used_at, revoked_at`
+
+func scanAPIKey(row rowScanner) (*models.APIKey, error) {
+	var (
+		k      models.APIKey
+		scopes []byte
+	)
+	if err := row.Scan(&k.ID, &k.TenantID, &k.Name, &k.Prefix, &scopes, &k.CreatedAt, &k.LastUsedAt, &k.RevokedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(scopes, &k.Scopes); err != nil {
+		return nil, err
+	}
+	return &k, nil
+}
+
+type PostgresAPIKeyRepo struct {
+	DB *dbpg.DB
+}
+
+func NewPostgresAPIKeyRepo(db *dbpg.DB) *PostgresAPIKeyRepo {
+	return &PostgresAPIKeyRepo{
+		DB: db,
+	}
+}
+
+// Create сохраняет ключ; вместо самого ключа хранится его хеш
+func (r *PostgresAPIKeyRepo) Create(ctx context.Context, k *models.APIKey, hash string) error {
+	scopes, err := json.Marshal(k.Scopes)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO api_keys(tenant_id, name, prefix, key_hash, scopes)
+		VALUES($1,$2,$3,$4,$5)
+		RETURNING id, created_at
+	`
+	return r.DB.QueryRowContext(ctx, query,
+		k.TenantID, k.Name, k.Prefix, hash, scopes,
+	).Scan(&k.ID, &k.CreatedAt)
+}
+
+// GetByHash возвращает действующий (не отозванный) ключ по хешу
+func (r *PostgresAPIKeyRepo) GetByHash(ctx context.Context, hash string) (*models.APIKey, error) {
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys WHERE key_hash = $1 AND revoked_at IS NULL`
+	return scanAPIKey(r.DB.QueryRowContext(ctx, query, hash))
+}
+
+// List возвращает ключи арендатора, а при пустом tenantID — все ключи
+func (r *PostgresAPIKeyRepo) List(ctx context.Context, tenantID string) ([]*models.APIKey, error) {
+	rows, err := r.DB.QueryContext(ctx, `
+		SELECT `+apiKeyColumns+`
+		FROM api_keys
+		WHERE $1 = '' OR tenant_id = $1
+		ORDER BY created_at DESC
+	`, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []*models.APIKey
+	for rows.Next() {
+		k, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, k)
+	}
+
+	return result, rows.Err()
+}
+
+// Revoke отзывает ключ. Возвращает sql.ErrNoRows, если ключа нет или он уже отозван.
+func (r *PostgresAPIKeyRepo) Revoke(ctx context.Context, id int) error {
+	res, err := r.DB.ExecContext(ctx, `
+		UPDATE api_keys
+		SET revoked_at = NOW()
+		WHERE id = $1 AND revoked_at IS NULL
+	`, id)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// Touch запоминает время использования ключа не чаще раза в минуту
+func (r *PostgresAPIKeyRepo) Touch(ctx context.Context, id string) error {
+	_, err := r.DB.ExecContext(ctx, `
+		UPDATE api_keys
+		SET last_used_at = NOW()
+		WHERE id = $1
+		AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute')
+	`, id)
+	return err
+}
@@ -0,0 +1,18 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"delayed-notifier/internal/models"
+)
+
+type DeadLetterRepo interface {
+	Add(ctx context.Context, d *models.DeadLetter) error
+	GetAll(ctx context.Context) ([]*models.DeadLetter, error)
+	GetBatch(ctx context.Context, afterID int, until time.Time, limit int) ([]*models.DeadLetter, error)
+	GetByID(ctx context.Context, id int) (*models.DeadLetter, error)
+	Delete(ctx context.Context, id int) error
+	DeleteAll(ctx context.Context) (int64, error)
+	Count(ctx context.Context) (int, error)
+}
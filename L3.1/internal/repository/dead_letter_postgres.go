@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"delayed-notifier/internal/models"
+
+	"github.com/wb-go/wbf/dbpg"
+)
+
+type PostgresDeadLetterRepo struct {
+	DB *dbpg.DB
+}
+
+func NewPostgresDeadLetterRepo(db *dbpg.DB) *PostgresDeadLetterRepo {
+	return &PostgresDeadLetterRepo{
+		DB: db,
+	}
+}
+
+func (r *PostgresDeadLetterRepo) Add(ctx context.Context, d *models.DeadLetter) error {
+	query := `
+		INSERT INTO dead_letters(notification_id, reason, payload)
+		VALUES($1,$2,$3)
+		RETURNING id, failed_at
+	`
+	return r.DB.QueryRowContext(ctx, query,
+		d.NotificationID, d.Reason, d.Payload,
+	).Scan(&d.ID, &d.FailedAt)
+}
+
+func (r *PostgresDeadLetterRepo) GetAll(ctx context.Context) ([]*models.DeadLetter, error) {
+	rows, err := r.DB.QueryContext(ctx, `
+		SELECT id, notification_id, reason, payload, failed_at
+		FROM dead_letters
+		ORDER BY failed_at DESC
+		LIMIT 100
+	`)
+	if err != nil {
+		return nil, err
+	}
+	return scanDeadLetters(rows)
+}
+
+// GetBatch возвращает до limit записей с id больше afterID, попавших в DLQ не позже until,
+// в порядке id. Используется для прохода по всей очереди пачками.
+func (r *PostgresDeadLetterRepo) GetBatch(ctx context.Context, afterID int, until time.Time, limit int) ([]*models.DeadLetter, error) {
+	rows, err := r.DB.QueryContext(ctx, `
+		SELECT id, notification_id, reason, payload, failed_at
+		FROM dead_letters
+		WHERE id > $1 AND failed_at <= $2
+		ORDER BY id
+		LIMIT $3
+	`, afterID, until, limit)
+	if err != nil {
+		return nil, err
+	}
+	return scanDeadLetters(rows)
+}
+
+func scanDeadLetters(rows *sql.Rows) ([]*models.DeadLetter, error) {
+	defer rows.Close()
+
+	var result []*models.DeadLetter
+	for rows.Next() {
+		var d models.DeadLetter
+		if err := rows.Scan(&d.ID, &d.NotificationID, &d.Reason, &d.Payload, &d.FailedAt); err != nil {
+			return nil, err
+		}
+		result = append(result, &d)
+	}
+
+	return result, rows.Err()
+}
+
+func (r *PostgresDeadLetterRepo) GetByID(ctx context.Context, id int) (*models.DeadLetter, error) {
+	query := `SELECT id, notification_id, reason, payload, failed_at FROM dead_letters WHERE id=$1`
+
+	var d models.DeadLetter
+	err := r.DB.QueryRowContext(ctx, query, id).Scan(&d.ID, &d.NotificationID, &d.Reason, &d.Payload, &d.FailedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+func (r *PostgresDeadLetterRepo) Delete(ctx context.Context, id int) error {
+	_, err := r.DB.ExecContext(ctx, `DELETE FROM dead_letters WHERE id = $1`, id)
+	return err
+}
+
+func (r *PostgresDeadLetterRepo) DeleteAll(ctx context.Context) (int64, error) {
+	res, err := r.DB.ExecContext(ctx, `DELETE FROM dead_letters`)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// Count возвращает число недоставленных уведомлений
+func (r *PostgresDeadLetterRepo) Count(ctx context.Context) (int, error) {
+	var n int
+	err := r.DB.QueryRowContext(ctx, `SELECT COUNT(*) FROM dead_letters`).Scan(&n)
+	return n, err
+}
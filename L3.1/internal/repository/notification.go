@@ -2,16 +2,33 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"delayed-notifier/internal/models"
 )
 
 type NotificationRepo interface {
 	Create(ctx context.Context, n *models.Notification) error
+	CreateBatch(ctx context.Context, ns []*models.Notification) ([]bool, error)
 	GetByID(ctx context.Context, id int) (*models.Notification, error)
-	GetActive(ctx context.Context) ([]*models.Notification, error)
-	Delete(ctx context.Context, id int) error
+	GetByIdempotencyKey(ctx context.Context, key string) (*models.Notification, error)
+	GetForReconcile(ctx context.Context, since time.Time, afterID, limit int) ([]*models.Notification, error)
+	Update(ctx context.Context, id int, patch models.NotificationPatch) (*models.Notification, error)
+	Cancel(ctx context.Context, id int) error
 	UpdateStatus(ctx context.Context, id int, status models.StatusType) error
-	GetAll(ctx context.Context) ([]*models.Notification, error)
+	TransitionStatus(ctx context.Context, id int, from, to models.StatusType) (bool, error)
+	GetAll(ctx context.Context, filter models.NotificationFilter) ([]*models.Notification, error)
 	UpdateRetryCount(ctx context.Context, id int, retryCount int) error
+	SwitchTarget(ctx context.Context, id int, index int) error
+	MarkDelivered(ctx context.Context, id int, status models.StatusType, channel models.ChannelType) error
+	ClaimDigest(ctx context.Context, lead *models.Notification, until time.Time, limit int, lease time.Duration) (int, []*models.Notification, error)
+	ReleaseDigest(ctx context.Context, digestID int) error
+	MarkDigestSent(ctx context.Context, digestID int, message string, channel models.ChannelType) ([]*models.Notification, error)
+	GetSeries(ctx context.Context, seriesID int) ([]*models.Notification, error)
+	CancelSeries(ctx context.Context, seriesID int) ([]int, error)
+	ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]*models.Notification, error)
+	Reschedule(ctx context.Context, id int, at time.Time) error
+	MarkQueued(ctx context.Context, id int, at time.Time) error
+	AddAttempt(ctx context.Context, a *models.Attempt) error
+	GetAttempts(ctx context.Context, id int) ([]*models.Attempt, error)
 }
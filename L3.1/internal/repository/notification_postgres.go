@@ -2,12 +2,69 @@ package repository
 
 import (
 	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
 	"delayed-notifier/internal/models"
+	"delayed-notifier/internal/tenant"
 
 	"github.com/wb-go/wbf/dbpg"
 )
 
+// notificationColumns — порядок колонок, который ожидает scanNotification
+const notificationColumns = `id, user_id, channel, recipient, message, send_at, status, retry_count, version, created_at, updated_at,
+	schedule, repeat_until, max_occurrences, COALESCE(series_id, id), occurrence, last_error,
+	template, locale, variables, time_zone, fallbacks, target_index, delivered_channel,
+	COALESCE(idempotency_key, ''), subject, html, email, COALESCE(digest_id::TEXT, ''), tenant_id, priority`
+
+// Запросы от имени арендатора видят только его уведомления. Арендатор берётся
+// из контекста; без него (фоновая обработка, административный токен) параметр
+// пуст и условие не ограничивает выборку.
+const tenantCond = `($%d::TEXT = '' OR tenant_id = $%d)`
+
+// scope возвращает условие на арендатора для параметра с номером n и значение параметра
+func scope(ctx context.Context, n int) (string, string) {
+	id, _ := tenant.FromContext(ctx)
+	return fmt.Sprintf(tenantCond, n, n), id
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanNotification(row rowScanner) (*models.Notification, error) {
+	var n models.Notification
+	err := row.Scan(
+		&n.ID, &n.UserID, &n.Channel, &n.Recipient, &n.Message, &n.SendAt, &n.Status, &n.Retry, &n.Version, &n.CreatedAt, &n.UpdatedAt,
+		&n.Schedule, &n.RepeatUntil, &n.MaxOccurrences, &n.SeriesID, &n.Occurrence, &n.LastError,
+		&n.Template, &n.Locale, &n.Variables, &n.TimeZone, &n.Fallbacks, &n.TargetIndex, &n.DeliveredChannel,
+		&n.IdempotencyKey, &n.Subject, &n.HTML, &n.Email, &n.DigestID, &n.TenantID, &n.Priority,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &n, nil
+}
+
+func scanNotifications(rows *sql.Rows) ([]*models.Notification, error) {
+	defer rows.Close()
+
+	var result []*models.Notification
+	for rows.Next() {
+		n, err := scanNotification(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, n)
+	}
+
+	return result, rows.Err()
+}
+
 type PostgresNotificationRepo struct {
 	DB *dbpg.DB
 }
@@ -18,87 +75,263 @@ func NewPostgresNotificationRepo(db *dbpg.DB) *PostgresNotificationRepo {
 	}
 }
 
-func (r *PostgresNotificationRepo) Create(ctx context.Context, n *models.Notification) error {
-	query := `
-		INSERT INTO notifications(user_id, channel, recipient, message, send_at)
-		VALUES($1,$2,$3,$4,$5)
-		RETURNING id, status, retry_count, created_at, updated_at
-	`
-	err := r.DB.QueryRowContext(ctx, query,
+// rowQuerier — *dbpg.DB или *sql.Tx
+type rowQuerier interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+const insertNotification = `
+	INSERT INTO notifications(user_id, channel, recipient, message, send_at,
+		schedule, repeat_until, max_occurrences, series_id, occurrence,
+		template, locale, variables, time_zone, fallbacks, idempotency_key,
+		subject, html, email, tenant_id, priority, target_index)
+	VALUES($1,$2,$3,$4,$5,$6,$7,$8,NULLIF($9, '')::INT,GREATEST($10, 1),$11,$12,$13,$14,$15,NULLIF($16, ''),$17,$18,$19,$20,$21,$22)
+	ON CONFLICT DO NOTHING
+	RETURNING id, status, retry_count, version, created_at, updated_at, COALESCE(series_id, id), occurrence
+`
+
+func insert(ctx context.Context, q rowQuerier, n *models.Notification) error {
+	return q.QueryRowContext(ctx, insertNotification,
 		n.UserID, n.Channel, n.Recipient, n.Message, n.SendAt,
-	).Scan(&n.ID, &n.Status, &n.Retry, &n.CreatedAt, &n.UpdatedAt)
-	return err
+		n.Schedule, n.RepeatUntil, n.MaxOccurrences, n.SeriesID, n.Occurrence,
+		n.Template, n.Locale, n.Variables, n.TimeZone, n.Fallbacks, n.IdempotencyKey,
+		n.Subject, n.HTML, n.Email, n.TenantID, n.Priority, n.TargetIndex,
+	).Scan(&n.ID, &n.Status, &n.Retry, &n.Version, &n.CreatedAt, &n.UpdatedAt, &n.SeriesID, &n.Occurrence)
 }
 
-func (r *PostgresNotificationRepo) GetByID(ctx context.Context, id int) (*models.Notification, error) {
-	query := `SELECT id, user_id, channel, recipient, message, send_at, status, retry_count, created_at, updated_at FROM notifications WHERE id=$1`
-	row := r.DB.QueryRowContext(ctx, query, id)
+// Create сохраняет уведомление и сообщение для очереди в outbox одной транзакцией.
+// Если уведомление с таким же ключом идемпотентности или такое же срабатывание
+// серии уже есть, возвращает sql.ErrNoRows.
+func (r *PostgresNotificationRepo) Create(ctx context.Context, n *models.Notification) error {
+	tx, err := r.DB.Master.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
 
-	var n models.Notification
-	err := row.Scan(&n.ID, &n.UserID, &n.Channel, &n.Recipient, &n.Message, &n.SendAt, &n.Status, &n.Retry, &n.CreatedAt, &n.UpdatedAt)
+	if err := insert(ctx, tx, n); err != nil {
+		return err
+	}
+	if err := insertOutbox(ctx, tx, n); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// CreateBatch в одной транзакции сохраняет уведомления и сообщения для очереди в outbox.
+// Для уведомлений, чей ключ идемпотентности уже занят, n заменяется сохранённым
+// уведомлением, а в результате на его месте false.
+func (r *PostgresNotificationRepo) CreateBatch(ctx context.Context, ns []*models.Notification) ([]bool, error) {
+	tx, err := r.DB.Master.BeginTx(ctx, nil)
 	if err != nil {
 		return nil, err
 	}
-	return &n, nil
+	defer func() { _ = tx.Rollback() }()
+
+	created := make([]bool, len(ns))
+	for i, n := range ns {
+		err := insert(ctx, tx, n)
+		if errors.Is(err, sql.ErrNoRows) && n.IdempotencyKey != "" {
+			existing, err := scanNotification(tx.QueryRowContext(ctx,
+				`SELECT `+notificationColumns+` FROM notifications WHERE tenant_id = $1 AND idempotency_key = $2`,
+				n.TenantID, n.IdempotencyKey,
+			))
+			if err != nil {
+				return nil, err
+			}
+			*n = *existing
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if err := insertOutbox(ctx, tx, n); err != nil {
+			return nil, err
+		}
+		created[i] = true
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return created, nil
 }
 
-func (r *PostgresNotificationRepo) GetActive(ctx context.Context) ([]*models.Notification, error) {
+func (r *PostgresNotificationRepo) GetByID(ctx context.Context, id int) (*models.Notification, error) {
+	cond, tenantID := scope(ctx, 2)
+	query := `SELECT ` + notificationColumns + ` FROM notifications WHERE id=$1 AND ` + cond
+	return scanNotification(r.DB.QueryRowContext(ctx, query, id, tenantID))
+}
+
+// GetByIdempotencyKey возвращает уведомление арендатора из контекста,
+// созданное с ключом идемпотентности key
+func (r *PostgresNotificationRepo) GetByIdempotencyKey(ctx context.Context, key string) (*models.Notification, error) {
+	tenantID, _ := tenant.FromContext(ctx)
+	query := `SELECT ` + notificationColumns + ` FROM notifications WHERE tenant_id=$1 AND idempotency_key=$2`
+	return scanNotification(r.DB.QueryRowContext(ctx, query, tenantID, key))
+}
+
+// GetForReconcile возвращает по возрастанию id, начиная после afterID, уведомления,
+// которые должны совпадать с кэшем: запланированные и изменённые не раньше since
+func (r *PostgresNotificationRepo) GetForReconcile(ctx context.Context, since time.Time, afterID, limit int) ([]*models.Notification, error) {
 	rows, err := r.DB.QueryContext(ctx, `
-		SELECT id, user_id, channel, recipient, message, send_at, status, retry_count, created_at, updated_at
+		SELECT `+notificationColumns+`
 		FROM notifications
-		WHERE status = $1
-		AND send_at >= NOW()
-	`, models.Scheduled)
+		WHERE (status = $1 OR updated_at >= $2)
+		AND id > $3
+		ORDER BY id
+		LIMIT $4
+	`, models.Scheduled, since, afterID, limit)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
+	return scanNotifications(rows)
+}
 
-	var result []*models.Notification
-	for rows.Next() {
-		var n models.Notification
-		if err := rows.Scan(&n.ID, &n.UserID, &n.Channel, &n.Recipient, &n.Message, &n.SendAt, &n.Status, &n.Retry, &n.CreatedAt, &n.UpdatedAt); err != nil {
-			return nil, err
-		}
-		result = append(result, &n)
+// GetAll возвращает уведомления по фильтру, от новых к старым.
+// Пагинация по ключу (created_at, id): следующая страница начинается после f.After.
+func (r *PostgresNotificationRepo) GetAll(ctx context.Context, f models.NotificationFilter) ([]*models.Notification, error) {
+	var (
+		conds []string
+		args  []any
+	)
+	arg := func(v any) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
 	}
 
-	return result, rows.Err()
+	if id, ok := tenant.FromContext(ctx); ok {
+		conds = append(conds, "tenant_id = "+arg(id))
+	}
+	if f.UserID != "" {
+		conds = append(conds, "user_id = "+arg(f.UserID))
+	}
+	if f.Status != nil {
+		conds = append(conds, "status = "+arg(*f.Status))
+	}
+	if f.Channel != nil {
+		conds = append(conds, "channel = "+arg(*f.Channel))
+	}
+	if f.Priority != nil {
+		conds = append(conds, "priority = "+arg(*f.Priority))
+	}
+	if f.SendFrom != nil {
+		conds = append(conds, "send_at >= "+arg(*f.SendFrom))
+	}
+	if f.SendTo != nil {
+		conds = append(conds, "send_at < "+arg(*f.SendTo))
+	}
+	if f.Recipient != "" {
+		conds = append(conds, "recipient ILIKE "+arg("%"+likeEscaper.Replace(f.Recipient)+"%"))
+	}
+	if f.After != nil {
+		conds = append(conds, "(created_at, id) < ("+arg(f.After.CreatedAt)+", "+arg(f.After.ID)+")")
+	}
+
+	query := `SELECT ` + notificationColumns + ` FROM notifications`
+	if len(conds) > 0 {
+		query += ` WHERE ` + strings.Join(conds, " AND ")
+	}
+	query += ` ORDER BY created_at DESC, id DESC LIMIT ` + arg(f.Limit)
+
+	rows, err := r.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return scanNotifications(rows)
 }
 
-func (r *PostgresNotificationRepo) GetAll(ctx context.Context) ([]*models.Notification, error) {
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// GetSeries возвращает все срабатывания серии в порядке их номера
+func (r *PostgresNotificationRepo) GetSeries(ctx context.Context, seriesID int) ([]*models.Notification, error) {
+	cond, tenantID := scope(ctx, 2)
 	rows, err := r.DB.QueryContext(ctx, `
-		SELECT id, user_id, channel, recipient, message, send_at, status, retry_count, created_at, updated_at
+		SELECT `+notificationColumns+`
 		FROM notifications
-		ORDER BY created_at DESC
-		LIMIT 100;
-	`)
+		WHERE (id = $1 OR series_id = $1) AND `+cond+`
+		ORDER BY occurrence
+	`, seriesID, tenantID)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
+	return scanNotifications(rows)
+}
 
-	var result []*models.Notification
-	for rows.Next() {
-		var n models.Notification
-		if err := rows.Scan(&n.ID, &n.UserID, &n.Channel, &n.Recipient, &n.Message, &n.SendAt, &n.Status, &n.Retry, &n.CreatedAt, &n.UpdatedAt); err != nil {
-			return nil, err
-		}
-		result = append(result, &n)
+// Update меняет запланированное уведомление, увеличивает его версию и в той же
+// транзакции кладёт новую версию в outbox.
+// Возвращает sql.ErrNoRows, если уведомления нет или оно уже не в статусе Scheduled.
+func (r *PostgresNotificationRepo) Update(ctx context.Context, id int, patch models.NotificationPatch) (*models.Notification, error) {
+	tx, err := r.DB.Master.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
 	}
+	defer func() { _ = tx.Rollback() }()
 
-	return result, rows.Err()
+	cond, tenantID := scope(ctx, 6)
+	query := `
+		UPDATE notifications
+		SET send_at = COALESCE($1, send_at),
+			message = COALESCE($2, message),
+			recipient = COALESCE($3, recipient),
+			version = version + 1,
+			visible_at = NULL,
+			updated_at = NOW()
+		WHERE id = $4 AND status = $5 AND ` + cond + `
+		RETURNING ` + notificationColumns
+	n, err := scanNotification(tx.QueryRowContext(ctx, query,
+		patch.SendAt, patch.Message, patch.Recipient, id, models.Scheduled, tenantID,
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := insertOutbox(ctx, tx, n); err != nil {
+		return nil, err
+	}
+	return n, tx.Commit()
 }
 
-func (r *PostgresNotificationRepo) Delete(ctx context.Context, id int) error {
+// Cancel помечает уведомление отменённым; строка остаётся в истории
+func (r *PostgresNotificationRepo) Cancel(ctx context.Context, id int) error {
+	cond, tenantID := scope(ctx, 3)
 	_, err := r.DB.ExecContext(ctx, `
 		UPDATE notifications
 		SET status = $1, updated_at = NOW()
-		WHERE id = $2
-	`, models.Canceled, id)
+		WHERE id = $2 AND `+cond, models.Canceled, id, tenantID)
 	return err
 }
 
+// CancelSeries отменяет все ещё не отправленные срабатывания серии и возвращает их ID
+func (r *PostgresNotificationRepo) CancelSeries(ctx context.Context, seriesID int) ([]int, error) {
+	cond, tenantID := scope(ctx, 4)
+	rows, err := r.DB.QueryContext(ctx, `
+		UPDATE notifications
+		SET status = $1, updated_at = NOW()
+		WHERE (id = $2 OR series_id = $2)
+		AND status = $3
+		AND `+cond+`
+		RETURNING id
+	`, models.Canceled, seriesID, models.Scheduled, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
 func (r *PostgresNotificationRepo) UpdateStatus(ctx context.Context, id int, status models.StatusType) error {
 	_, err := r.DB.ExecContext(ctx, `
 		UPDATE notifications
@@ -108,6 +341,21 @@ func (r *PostgresNotificationRepo) UpdateStatus(ctx context.Context, id int, sta
 	return err
 }
 
+// TransitionStatus меняет статус уведомления с from на to и сообщает, был ли он from.
+// Из двух одновременных переходов из одного статуса выполняется только один.
+func (r *PostgresNotificationRepo) TransitionStatus(ctx context.Context, id int, from, to models.StatusType) (bool, error) {
+	res, err := r.DB.ExecContext(ctx, `
+		UPDATE notifications
+		SET status = $1, updated_at = NOW()
+		WHERE id = $2 AND status = $3
+	`, to, id, from)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
 func (r *PostgresNotificationRepo) UpdateRetryCount(ctx context.Context, id int, retryCount int) error {
 	_, err := r.DB.ExecContext(ctx, `
 		UPDATE notifications
@@ -116,3 +364,227 @@ func (r *PostgresNotificationRepo) UpdateRetryCount(ctx context.Context, id int,
 	`, retryCount, id)
 	return err
 }
+
+// SwitchTarget переводит уведомление на канал цепочки с номером index и обнуляет счётчик попыток
+func (r *PostgresNotificationRepo) SwitchTarget(ctx context.Context, id int, index int) error {
+	_, err := r.DB.ExecContext(ctx, `
+		UPDATE notifications
+		SET target_index = $1, retry_count = 0, updated_at = NOW()
+		WHERE id = $2
+	`, index, id)
+	return err
+}
+
+// MarkDelivered сохраняет статус доставленного уведомления и канал, которым оно доставлено
+func (r *PostgresNotificationRepo) MarkDelivered(ctx context.Context, id int, status models.StatusType, channel models.ChannelType) error {
+	_, err := r.DB.ExecContext(ctx, `
+		UPDATE notifications
+		SET status = $1, delivered_channel = $2, updated_at = NOW()
+		WHERE id = $3
+	`, status, channel, id)
+	return err
+}
+
+// ClaimDue захватывает наступившие уведомления, пропуская строки, заблокированные другими репликами.
+// Захваченные строки скрываются от повторного захвата на время lease; срочные захватываются первыми.
+func (r *PostgresNotificationRepo) ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]*models.Notification, error) {
+	rows, err := r.DB.QueryContext(ctx, `
+		WITH due AS (
+			SELECT id AS due_id
+			FROM notifications
+			WHERE status = $1
+			AND COALESCE(visible_at, send_at) <= NOW()
+			ORDER BY priority DESC, COALESCE(visible_at, send_at)
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+		UPDATE notifications
+		SET visible_at = NOW() + make_interval(secs => $3)
+		FROM due
+		WHERE notifications.id = due.due_id
+		RETURNING `+notificationColumns+`
+	`, models.Scheduled, limit, lease.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	return scanNotifications(rows)
+}
+
+// Reschedule задаёт момент, начиная с которого уведомление доступно для ClaimDue
+func (r *PostgresNotificationRepo) Reschedule(ctx context.Context, id int, at time.Time) error {
+	_, err := r.DB.ExecContext(ctx, `
+		UPDATE notifications
+		SET visible_at = $1
+		WHERE id = $2
+	`, at, id)
+	return err
+}
+
+// MarkQueued запоминает, что сообщение уведомления снова опубликовано и его
+// обработки следует ждать к at. По этому времени находятся сообщения, потерянные брокером.
+func (r *PostgresNotificationRepo) MarkQueued(ctx context.Context, id int, at time.Time) error {
+	_, err := r.DB.ExecContext(ctx, `
+		UPDATE notifications
+		SET queued_until = GREATEST($1, NOW())
+		WHERE id = $2
+	`, at, id)
+	return err
+}
+
+// AddAttempt записывает попытку отправки; текст ошибки неудачной попытки
+// сохраняется и в notifications.last_error
+func (r *PostgresNotificationRepo) AddAttempt(ctx context.Context, a *models.Attempt) error {
+	query := `
+		WITH updated AS (
+			UPDATE notifications
+			SET last_error = $5
+			WHERE id = $1 AND $5 <> ''
+		)
+		INSERT INTO notification_attempts(notification_id, channel, attempted_at, duration_ms, error)
+		VALUES($1,$2,$3,$4,$5)
+		RETURNING id
+	`
+	return r.DB.QueryRowContext(ctx, query,
+		a.NotificationID, a.Channel, a.AttemptedAt, a.DurationMs, a.Error,
+	).Scan(&a.ID)
+}
+
+// GetAttempts возвращает попытки отправки уведомления в хронологическом порядке
+func (r *PostgresNotificationRepo) GetAttempts(ctx context.Context, id int) ([]*models.Attempt, error) {
+	cond, tenantID := scope(ctx, 2)
+	rows, err := r.DB.QueryContext(ctx, `
+		SELECT a.id, a.notification_id, a.channel, a.attempted_at, a.duration_ms, a.error
+		FROM notification_attempts a
+		JOIN notifications ON notifications.id = a.notification_id
+		WHERE a.notification_id = $1 AND `+cond+`
+		ORDER BY a.attempted_at
+	`, id, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []*models.Attempt
+	for rows.Next() {
+		var a models.Attempt
+		if err := rows.Scan(&a.ID, &a.NotificationID, &a.Channel, &a.AttemptedAt, &a.DurationMs, &a.Error); err != nil {
+			return nil, err
+		}
+		result = append(result, &a)
+	}
+
+	return result, rows.Err()
+}
+
+// ClaimDigest собирает в дайджест запланированные уведомления того же пользователя,
+// канала и получателя, что и lead, со временем отправки не позже until.
+// Уведомления чужого неотправленного дайджеста пропускаются, пока он моложе lease;
+// срочные (PriorityHigh и выше) в дайджест не собираются.
+// Если lead уже занят или собирать не с чем, дайджест не создаётся:
+// возвращается 0 и найденные свободные уведомления.
+func (r *PostgresNotificationRepo) ClaimDigest(ctx context.Context, lead *models.Notification, until time.Time, limit int, lease time.Duration) (int, []*models.Notification, error) {
+	tx, err := r.DB.Master.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var digestID int
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO digests(tenant_id, user_id, channel, recipient)
+		VALUES($1,$2,$3,$4)
+		RETURNING id
+	`, lead.TenantID, lead.UserID, lead.Channel, lead.Recipient).Scan(&digestID)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		WITH candidates AS (
+			SELECT n.id AS candidate_id
+			FROM notifications n
+			LEFT JOIN digests d ON d.id = n.digest_id
+			WHERE n.status = $2
+			AND n.tenant_id = $11 AND n.user_id = $3 AND n.channel = $4 AND n.recipient = $5
+			AND n.target_index = 0 AND n.email IS NULL AND n.priority < $10
+			AND (n.id = $6 OR n.send_at <= $7)
+			AND (n.digest_id IS NULL OR (d.sent_at IS NULL AND d.created_at < NOW() - make_interval(secs => $8)))
+			ORDER BY n.id = $6 DESC, n.send_at
+			LIMIT $9
+			FOR UPDATE OF n SKIP LOCKED
+		)
+		UPDATE notifications
+		SET digest_id = $1, updated_at = NOW()
+		FROM candidates
+		WHERE notifications.id = candidates.candidate_id
+		RETURNING `+notificationColumns+`
+	`, digestID, models.Scheduled, lead.UserID, lead.Channel, lead.Recipient, lead.ID, until, lease.Seconds(), limit, models.PriorityHigh, lead.TenantID)
+	if err != nil {
+		return 0, nil, err
+	}
+	members, err := scanNotifications(rows)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	claimedLead := false
+	for _, m := range members {
+		if m.ID == lead.ID {
+			claimedLead = true
+		}
+	}
+	if !claimedLead || len(members) < 2 {
+		return 0, members, nil
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, nil, err
+	}
+	return digestID, members, nil
+}
+
+// ReleaseDigest удаляет неотправленный дайджест; его уведомления снова свободны
+func (r *PostgresNotificationRepo) ReleaseDigest(ctx context.Context, digestID int) error {
+	_, err := r.DB.ExecContext(ctx, `
+		DELETE FROM digests
+		WHERE id = $1 AND sent_at IS NULL
+	`, digestID)
+	return err
+}
+
+// MarkDigestSent сохраняет текст отправленного дайджеста и помечает его уведомления отправленными
+func (r *PostgresNotificationRepo) MarkDigestSent(ctx context.Context, digestID int, message string, channel models.ChannelType) ([]*models.Notification, error) {
+	tx, err := r.DB.Master.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE digests
+		SET message = $1, sent_at = NOW()
+		WHERE id = $2
+	`, message, digestID)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		UPDATE notifications
+		SET status = $1, delivered_channel = $2, updated_at = NOW()
+		WHERE digest_id = $3 AND status = $4
+		RETURNING `+notificationColumns,
+		models.Sent, channel, digestID, models.Scheduled)
+	if err != nil {
+		return nil, err
+	}
+	members, err := scanNotifications(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return members, nil
+}
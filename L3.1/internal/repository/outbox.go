@@ -0,0 +1,21 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"delayed-notifier/internal/models"
+)
+
+type OutboxRepo interface {
+	// Relay передаёт publish до limit сообщений, чья очередь попытки наступила.
+	// Опубликованные помечаются, неудачные откладываются на retryDelay(attempts).
+	Relay(ctx context.Context, limit int, publish func(m *models.OutboxMessage) error, retryDelay func(attempts int) time.Duration) (published int, failed int, err error)
+	// RequeueLost находит до limit запланированных уведомлений, которые ждали обработки
+	// раньше lostBefore и не имеют неопубликованного сообщения в outbox, увеличивает их версию
+	// и кладёт новые версии в outbox; копии, всё-таки оставшиеся в брокере, будут отброшены
+	RequeueLost(ctx context.Context, lostBefore time.Time, limit int) ([]*models.Notification, error)
+	// DeletePublished удаляет до limit сообщений, опубликованных раньше before
+	DeletePublished(ctx context.Context, before time.Time, limit int) (int64, error)
+	Stats(ctx context.Context) (*models.OutboxStats, error)
+}
@@ -0,0 +1,187 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log"
+	"time"
+
+	"delayed-notifier/internal/models"
+
+	"github.com/wb-go/wbf/dbpg"
+)
+
+type PostgresOutboxRepo struct {
+	DB *dbpg.DB
+}
+
+func NewPostgresOutboxRepo(db *dbpg.DB) *PostgresOutboxRepo {
+	return &PostgresOutboxRepo{
+		DB: db,
+	}
+}
+
+// insertOutbox записывает текущую версию уведомления в outbox внутри транзакции tx
+// и запоминает, когда ждать обработки этого сообщения
+func insertOutbox(ctx context.Context, tx *sql.Tx, n *models.Notification) error {
+	payload, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO outbox(notification_id, payload, publish_at)
+		VALUES($1,$2,$3)
+	`, n.ID, string(payload), n.SendAt); err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE notifications
+		SET queued_until = GREATEST($1, NOW())
+		WHERE id = $2
+	`, n.SendAt, n.ID)
+	return err
+}
+
+// Relay блокирует пачку сообщений до конца транзакции, поэтому несколько реплик
+// могут разбирать outbox параллельно, не публикуя одно сообщение дважды
+func (r *PostgresOutboxRepo) Relay(ctx context.Context, limit int, publish func(m *models.OutboxMessage) error, retryDelay func(attempts int) time.Duration) (int, int, error) {
+	tx, err := r.DB.Master.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id, notification_id, payload, publish_at, attempts, last_error, next_attempt_at, created_at
+		FROM outbox
+		WHERE published_at IS NULL
+		AND next_attempt_at <= NOW()
+		ORDER BY id
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED
+	`, limit)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var pending []*models.OutboxMessage
+	for rows.Next() {
+		var m models.OutboxMessage
+		if err := rows.Scan(&m.ID, &m.NotificationID, &m.Payload, &m.PublishAt, &m.Attempts, &m.LastError, &m.NextAttemptAt, &m.CreatedAt); err != nil {
+			rows.Close()
+			return 0, 0, err
+		}
+		pending = append(pending, &m)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, 0, err
+	}
+
+	published, failed := 0, 0
+	for _, m := range pending {
+		if pubErr := publish(m); pubErr != nil {
+			log.Printf("outbox: failed to publish message %s (attempt %d): %v", m.ID, m.Attempts+1, pubErr)
+			if _, err := tx.ExecContext(ctx, `
+				UPDATE outbox
+				SET attempts = attempts + 1,
+					last_error = $1,
+					next_attempt_at = NOW() + make_interval(secs => $2)
+				WHERE id = $3
+			`, pubErr.Error(), retryDelay(m.Attempts+1).Seconds(), m.ID); err != nil {
+				return 0, 0, err
+			}
+			failed++
+			continue
+		}
+
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE outbox
+			SET published_at = NOW()
+			WHERE id = $1
+		`, m.ID); err != nil {
+			return 0, 0, err
+		}
+		published++
+	}
+
+	return published, failed, tx.Commit()
+}
+
+// RequeueLost выполняется одной транзакцией; строки блокируются с SKIP LOCKED,
+// поэтому реплики могут проверять потерянные сообщения одновременно.
+// Уведомления с неопубликованным сообщением в outbox ещё ждут relay и не трогаются.
+func (r *PostgresOutboxRepo) RequeueLost(ctx context.Context, lostBefore time.Time, limit int) ([]*models.Notification, error) {
+	tx, err := r.DB.Master.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	rows, err := tx.QueryContext(ctx, `
+		UPDATE notifications
+		SET version = version + 1, visible_at = NULL, updated_at = NOW()
+		WHERE id IN (
+			SELECT n.id FROM notifications n
+			WHERE n.status = $1
+			AND n.queued_until < $2
+			AND NOT EXISTS (
+				SELECT 1 FROM outbox o
+				WHERE o.notification_id = n.id AND o.published_at IS NULL
+			)
+			ORDER BY n.queued_until
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING `+notificationColumns, models.Scheduled, lostBefore, limit)
+	if err != nil {
+		return nil, err
+	}
+	requeued, err := scanNotifications(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, n := range requeued {
+		if err := insertOutbox(ctx, tx, n); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return requeued, nil
+}
+
+func (r *PostgresOutboxRepo) DeletePublished(ctx context.Context, before time.Time, limit int) (int64, error) {
+	res, err := r.DB.ExecContext(ctx, `
+		DELETE FROM outbox
+		WHERE id IN (
+			SELECT id FROM outbox
+			WHERE published_at < $1
+			ORDER BY published_at
+			LIMIT $2
+		)
+	`, before, limit)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func (r *PostgresOutboxRepo) Stats(ctx context.Context) (*models.OutboxStats, error) {
+	var st models.OutboxStats
+	err := r.DB.QueryRowContext(ctx, `
+		SELECT COUNT(*), COUNT(*) FILTER (WHERE attempts > 0), MIN(created_at)
+		FROM outbox
+		WHERE published_at IS NULL
+	`).Scan(&st.Pending, &st.Retrying, &st.Oldest)
+	if err != nil {
+		return nil, err
+	}
+	return &st, nil
+}
@@ -0,0 +1,12 @@
+package repository
+
+import (
+	"context"
+
+	"delayed-notifier/internal/models"
+)
+
+type UserProfileRepo interface {
+	Get(ctx context.Context, tenantID, userID string) (*models.UserProfile, error)
+	Upsert(ctx context.Context, p *models.UserProfile) error
+}
@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+
+	"delayed-notifier/internal/models"
+
+	"github.com/wb-go/wbf/dbpg"
+)
+
+type PostgresUserProfileRepo struct {
+	DB *dbpg.DB
+}
+
+func NewPostgresUserProfileRepo(db *dbpg.DB) *PostgresUserProfileRepo {
+	return &PostgresUserProfileRepo{
+		DB: db,
+	}
+}
+
+// Get возвращает профиль пользователя userID арендатора tenantID:
+// у разных арендаторов один и тот же user_id — разные люди
+func (r *PostgresUserProfileRepo) Get(ctx context.Context, tenantID, userID string) (*models.UserProfile, error) {
+	query := `
+		SELECT tenant_id, user_id, time_zone, quiet_start, quiet_end, preferred_channels, digest_window, updated_at
+		FROM user_profiles
+		WHERE tenant_id = $1 AND user_id = $2
+	`
+
+	var (
+		p        models.UserProfile
+		channels []byte
+	)
+	err := r.DB.QueryRowContext(ctx, query, tenantID, userID).Scan(
+		&p.TenantID, &p.UserID, &p.TimeZone, &p.QuietStart, &p.QuietEnd, &channels, &p.DigestWindow, &p.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(channels, &p.PreferredChannels); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (r *PostgresUserProfileRepo) Upsert(ctx context.Context, p *models.UserProfile) error {
+	if p.PreferredChannels == nil {
+		p.PreferredChannels = []models.ChannelType{}
+	}
+	channels, err := json.Marshal(p.PreferredChannels)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO user_profiles(tenant_id, user_id, time_zone, quiet_start, quiet_end, preferred_channels, digest_window)
+		VALUES($1,$2,$3,$4,$5,$6,$7)
+		ON CONFLICT (tenant_id, user_id) DO UPDATE
+		SET time_zone = EXCLUDED.time_zone,
+			quiet_start = EXCLUDED.quiet_start,
+			quiet_end = EXCLUDED.quiet_end,
+			preferred_channels = EXCLUDED.preferred_channels,
+			digest_window = EXCLUDED.digest_window,
+			updated_at = NOW()
+		RETURNING updated_at
+	`
+	return r.DB.QueryRowContext(ctx, query,
+		p.TenantID, p.UserID, p.TimeZone, p.QuietStart, p.QuietEnd, channels, p.DigestWindow,
+	).Scan(&p.UpdatedAt)
+}
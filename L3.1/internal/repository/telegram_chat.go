@@ -0,0 +1,15 @@
+package repository
+
+import (
+	"context"
+
+	"delayed-notifier/internal/models"
+)
+
+type TelegramChatRepo interface {
+	Upsert(ctx context.Context, chat *models.TelegramChat) error
+	Deactivate(ctx context.Context, chatID int64) error
+	GetActive(ctx context.Context) ([]*models.TelegramChat, error)
+	GetActiveByUsername(ctx context.Context, username string) (*models.TelegramChat, error)
+	GetAll(ctx context.Context) ([]*models.TelegramChat, error)
+}
@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"delayed-notifier/internal/models"
+
+	"github.com/wb-go/wbf/dbpg"
+)
+
+type PostgresTelegramChatRepo struct {
+	DB *dbpg.DB
+}
+
+func NewPostgresTelegramChatRepo(db *dbpg.DB) *PostgresTelegramChatRepo {
+	return &PostgresTelegramChatRepo{
+		DB: db,
+	}
+}
+
+// Upsert регистрирует чат или обновляет его username. Если username раньше
+// принадлежал другому чату (пользователь сменил ник), у старого чата он снимается.
+func (r *PostgresTelegramChatRepo) Upsert(ctx context.Context, chat *models.TelegramChat) error {
+	tx, err := r.DB.Master.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE telegram_chats
+		SET username = NULL, updated_at = NOW()
+		WHERE username = $1 AND chat_id <> $2
+	`, chat.Username, chat.ChatID); err != nil {
+		return err
+	}
+
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO telegram_chats(chat_id, username, first_name, active)
+		VALUES($1, NULLIF($2, ''), $3, TRUE)
+		ON CONFLICT (chat_id) DO UPDATE
+		SET username = EXCLUDED.username,
+			first_name = EXCLUDED.first_name,
+			active = TRUE,
+			updated_at = NOW()
+		RETURNING active, registered_at, updated_at
+	`, chat.ChatID, chat.Username, chat.FirstName).Scan(&chat.Active, &chat.RegisteredAt, &chat.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *PostgresTelegramChatRepo) Deactivate(ctx context.Context, chatID int64) error {
+	_, err := r.DB.ExecContext(ctx, `
+		UPDATE telegram_chats
+		SET active = FALSE, updated_at = NOW()
+		WHERE chat_id = $1
+	`, chatID)
+	return err
+}
+
+func (r *PostgresTelegramChatRepo) GetActive(ctx context.Context) ([]*models.TelegramChat, error) {
+	rows, err := r.DB.QueryContext(ctx, `
+		SELECT chat_id, COALESCE(username, ''), first_name, active, registered_at, updated_at
+		FROM telegram_chats
+		WHERE active AND username IS NOT NULL
+	`)
+	if err != nil {
+		return nil, err
+	}
+	return scanTelegramChats(rows)
+}
+
+// GetActiveByUsername возвращает активный чат пользователя или sql.ErrNoRows
+func (r *PostgresTelegramChatRepo) GetActiveByUsername(ctx context.Context, username string) (*models.TelegramChat, error) {
+	var c models.TelegramChat
+	err := r.DB.QueryRowContext(ctx, `
+		SELECT chat_id, COALESCE(username, ''), first_name, active, registered_at, updated_at
+		FROM telegram_chats
+		WHERE active AND username = $1
+	`, username).Scan(&c.ChatID, &c.Username, &c.FirstName, &c.Active, &c.RegisteredAt, &c.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (r *PostgresTelegramChatRepo) GetAll(ctx context.Context) ([]*models.TelegramChat, error) {
+	rows, err := r.DB.QueryContext(ctx, `
+		SELECT chat_id, COALESCE(username, ''), first_name, active, registered_at, updated_at
+		FROM telegram_chats
+		ORDER BY registered_at DESC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	return scanTelegramChats(rows)
+}
+
+func scanTelegramChats(rows *sql.Rows) ([]*models.TelegramChat, error) {
+	defer rows.Close()
+
+	var result []*models.TelegramChat
+	for rows.Next() {
+		var c models.TelegramChat
+		if err := rows.Scan(&c.ChatID, &c.Username, &c.FirstName, &c.Active, &c.RegisteredAt, &c.UpdatedAt); err != nil {
+			return nil, err
+		}
+		result = append(result, &c)
+	}
+
+	return result, rows.Err()
+}
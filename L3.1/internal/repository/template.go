@@ -0,0 +1,17 @@
+package repository
+
+import (
+	"context"
+
+	"delayed-notifier/internal/models"
+)
+
+type TemplateRepo interface {
+	Create(ctx context.Context, t *models.Template) error
+	GetByID(ctx context.Context, id int) (*models.Template, error)
+	GetAll(ctx context.Context) ([]*models.Template, error)
+	Update(ctx context.Context, t *models.Template) error
+	Delete(ctx context.Context, id int) error
+	// Find возвращает шаблон для первого подходящего языка из locales
+	Find(ctx context.Context, name string, channel models.ChannelType, locales []string) (*models.Template, error)
+}
@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"delayed-notifier/internal/models"
+
+	"github.com/wb-go/wbf/dbpg"
+)
+
+const templateColumns = `id, name, channel, locale, subject, body, html, created_at, updated_at`
+
+func scanTemplate(row rowScanner) (*models.Template, error) {
+	var t models.Template
+	err := row.Scan(&t.ID, &t.Name, &t.Channel, &t.Locale, &t.Subject, &t.Body, &t.HTML, &t.CreatedAt, &t.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+type PostgresTemplateRepo struct {
+	DB *dbpg.DB
+}
+
+func NewPostgresTemplateRepo(db *dbpg.DB) *PostgresTemplateRepo {
+	return &PostgresTemplateRepo{
+		DB: db,
+	}
+}
+
+func (r *PostgresTemplateRepo) Create(ctx context.Context, t *models.Template) error {
+	query := `
+		INSERT INTO templates(name, channel, locale, subject, body, html)
+		VALUES($1,$2,$3,$4,$5,$6)
+		RETURNING id, created_at, updated_at
+	`
+	return r.DB.QueryRowContext(ctx, query,
+		t.Name, t.Channel, t.Locale, t.Subject, t.Body, t.HTML,
+	).Scan(&t.ID, &t.CreatedAt, &t.UpdatedAt)
+}
+
+func (r *PostgresTemplateRepo) GetByID(ctx context.Context, id int) (*models.Template, error) {
+	query := `SELECT ` + templateColumns + ` FROM templates WHERE id=$1`
+	return scanTemplate(r.DB.QueryRowContext(ctx, query, id))
+}
+
+func (r *PostgresTemplateRepo) GetAll(ctx context.Context) ([]*models.Template, error) {
+	rows, err := r.DB.QueryContext(ctx, `
+		SELECT `+templateColumns+`
+		FROM templates
+		ORDER BY name, channel, locale
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []*models.Template
+	for rows.Next() {
+		t, err := scanTemplate(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, t)
+	}
+
+	return result, rows.Err()
+}
+
+func (r *PostgresTemplateRepo) Update(ctx context.Context, t *models.Template) error {
+	query := `
+		UPDATE templates
+		SET name = $1, channel = $2, locale = $3, subject = $4, body = $5, html = $6, updated_at = NOW()
+		WHERE id = $7
+		RETURNING created_at, updated_at
+	`
+	return r.DB.QueryRowContext(ctx, query,
+		t.Name, t.Channel, t.Locale, t.Subject, t.Body, t.HTML, t.ID,
+	).Scan(&t.CreatedAt, &t.UpdatedAt)
+}
+
+func (r *PostgresTemplateRepo) Delete(ctx context.Context, id int) error {
+	res, err := r.DB.ExecContext(ctx, `DELETE FROM templates WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *PostgresTemplateRepo) Find(ctx context.Context, name string, channel models.ChannelType, locales []string) (*models.Template, error) {
+	query := `
+		SELECT ` + templateColumns + `
+		FROM templates
+		WHERE name = $1 AND channel = $2 AND locale = ANY($3)
+		ORDER BY array_position($3, locale)
+		LIMIT 1
+	`
+	return scanTemplate(r.DB.QueryRowContext(ctx, query, name, channel, locales))
+}
@@ -0,0 +1,79 @@
+package sender
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"strings"
+
+	"delayed-notifier/internal/models"
+)
+
+// Digester собирает несколько уведомлений одному получателю в одно сообщение
+type Digester interface {
+	Digest(notifications []models.Notification) (models.Notification, error)
+}
+
+// Digest рендерит шаблоны уведомлений и объединяет их в одно сообщение
+func (r *Registry) Digest(notifications []models.Notification) (models.Notification, error) {
+	rendered := make([]models.Notification, len(notifications))
+	for i, n := range notifications {
+		rn, err := r.render(n)
+		if err != nil {
+			return models.Notification{}, fmt.Errorf("notification %s: %w", n.ID, err)
+		}
+		rendered[i] = rn
+	}
+	return combine(rendered)
+}
+
+// Digest делегирует сборку обёрнутому отправителю; лимит проверяется уже при отправке дайджеста
+func (r *RateLimitedSender) Digest(notifications []models.Notification) (models.Notification, error) {
+	if d, ok := r.next.(Digester); ok {
+		return d.Digest(notifications)
+	}
+	return combine(notifications)
+}
+
+// combine склеивает уже отрендеренные уведомления. Адресат и служебные поля
+// берутся из первого; если хотя бы одно уведомление в HTML, дайджест тоже в HTML.
+func combine(notifications []models.Notification) (models.Notification, error) {
+	if len(notifications) == 0 {
+		return models.Notification{}, errors.New("empty digest")
+	}
+
+	digest := notifications[0]
+	digest.Template = ""
+	digest.Variables = nil
+	digest.Subject = fmt.Sprintf("Уведомлений: %d", len(notifications))
+
+	digest.HTML = false
+	for _, n := range notifications {
+		if n.HTML {
+			digest.HTML = true
+		}
+	}
+
+	parts := make([]string, len(notifications))
+	for i, n := range notifications {
+		switch {
+		case !digest.HTML:
+			parts[i] = strings.TrimPrefix(n.Subject+"\n"+n.Message, "\n")
+		case n.HTML:
+			parts[i] = n.Message
+			if n.Subject != "" {
+				parts[i] = "<h3>" + html.EscapeString(n.Subject) + "</h3>\n" + n.Message
+			}
+		default:
+			text := strings.TrimPrefix(n.Subject+"\n"+n.Message, "\n")
+			parts[i] = strings.ReplaceAll(html.EscapeString(text), "\n", "<br>")
+		}
+	}
+
+	if digest.HTML {
+		digest.Message = strings.Join(parts, "\n<hr>\n")
+	} else {
+		digest.Message = strings.Join(parts, "\n\n———\n\n")
+	}
+	return digest, nil
+}
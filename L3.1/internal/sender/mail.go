@@ -1,7 +1,16 @@
 package sender
 
 import (
+	"errors"
+	"fmt"
+	"html"
+	"io"
 	"log"
+	"net/http"
+	netmail "net/mail"
+	"net/url"
+	"regexp"
+	"strings"
 	"time"
 
 	"delayed-notifier/internal/models"
@@ -9,63 +18,281 @@ import (
 	mail "github.com/xhit/go-simple-mail/v2"
 )
 
-// EmailSender хранит настройки SMTP
-type EmailSender struct {
-	Server   string
+// EmailConfig — настройки SMTP-сервера, пула соединений и загрузки вложений
+type EmailConfig struct {
+	Host     string
 	Port     int
 	Username string
 	Password string
 	From     string
+	// Encryption: "starttls", "ssl" или "none" (локальный SMTP вроде Mailpit)
+	Encryption string
+
+	// Сколько соединений держать открытыми и через сколько простоя их закрывать
+	PoolSize    int
+	IdleTimeout time.Duration
+
+	// Таймаут скачивания вложения по URL и предельный суммарный размер вложений письма
+	AttachmentTimeout time.Duration
+	MaxAttachmentSize int64
+	// AttachmentHosts — хосты, с которых можно скачивать вложения по URL:
+	// "files.example.com" или "*.example.com" для поддоменов. Пусто — вложения
+	// по URL запрещены, иначе через них можно вынести наружу ответы внутренних сервисов.
+	AttachmentHosts []string
+}
+
+// EmailSender отправляет письма через пул keep-alive SMTP-соединений
+type EmailSender struct {
+	From string
+	// Client скачивает вложения по URL; по умолчанию не соединяется с внутренними адресами
+	Client *http.Client
+
+	pool    *smtpPool
+	maxSize int64
+	hosts   []string
 }
 
 // NewEmailSender создает новый объект EmailSender
-func NewEmailSender(server string, port int, username, password, from string) *EmailSender {
-	return &EmailSender{
-		Server:   server,
-		Port:     port,
-		Username: username,
-		Password: password,
-		From:     from,
+func NewEmailSender(cfg EmailConfig) (*EmailSender, error) {
+	encryption, err := ParseEncryption(cfg.Encryption)
+	if err != nil {
+		return nil, err
+	}
+
+	server := mail.NewSMTPClient()
+	server.Host = cfg.Host
+	server.Port = cfg.Port
+	server.Username = cfg.Username
+	server.Password = cfg.Password
+	server.Encryption = encryption
+	server.KeepAlive = true
+	server.ConnectTimeout = 10 * time.Second
+	server.SendTimeout = 10 * time.Second
+
+	s := &EmailSender{
+		From:    cfg.From,
+		pool:    newSMTPPool(server, cfg.PoolSize, cfg.IdleTimeout),
+		maxSize: cfg.MaxAttachmentSize,
+		hosts:   cfg.AttachmentHosts,
+	}
+	// Перенаправления проверяются по списку хостов так же, как исходный URL
+	s.Client = newPublicClient(cfg.AttachmentTimeout, func(req *http.Request, via []*http.Request) error {
+		if len(via) >= 5 {
+			return errors.New("too many redirects")
+		}
+		_, err := s.attachmentURL(req.URL.String())
+		return err
+	})
+	return s, nil
+}
+
+// ParseEncryption возвращает способ шифрования SMTP по имени
+func ParseEncryption(s string) (mail.Encryption, error) {
+	switch strings.ToLower(s) {
+	case "", "starttls":
+		return mail.EncryptionSTARTTLS, nil
+	case "ssl", "tls":
+		return mail.EncryptionSSLTLS, nil
+	case "none":
+		return mail.EncryptionNone, nil
+	default:
+		return 0, fmt.Errorf("unknown SMTP encryption %q", s)
 	}
 }
 
+// defaultSubject — тема письма, если уведомление не задаёт свою
+const defaultSubject = "Напоминание"
+
 // SendEmail отправляет письмо на указанный адрес с заданным текстом
 func (s *EmailSender) SendEmail(to, body string) error {
-	// Настраиваем SMTP сервер
-	smtp := mail.NewSMTPClient()
-	smtp.Host = s.Server
-	smtp.Port = s.Port
-	smtp.Username = s.Username
-	smtp.Password = s.Password
-	smtp.Encryption = mail.EncryptionSTARTTLS
-	smtp.KeepAlive = false
-	smtp.ConnectTimeout = 10 * time.Second
-	smtp.SendTimeout = 10 * time.Second
-
-	// Подключаемся к SMTP серверу
-	client, err := smtp.Connect()
-	if err != nil {
-		log.Println("SMTP connection error:", err)
-		return err
+	return s.Send(models.Notification{Recipient: to, Message: body})
+}
+
+func (s *EmailSender) Send(notification models.Notification) error {
+	subject := notification.Subject
+	if subject == "" {
+		subject = defaultSubject
 	}
 
-	// Создаем письмо
 	email := mail.NewMSG()
 	email.SetFrom(s.From).
-		AddTo(to).
-		SetSubject("Напоминание").
-		SetBody(mail.TextPlain, body)
+		AddTo(notification.Recipient).
+		SetSubject(subject)
+
+	if notification.HTML {
+		// Клиенты показывают последнюю понятную им часть multipart/alternative
+		email.SetBody(mail.TextPlain, plainText(notification)).
+			AddAlternative(mail.TextHTML, notification.Message)
+	} else {
+		email.SetBody(mail.TextPlain, notification.Message)
+	}
+
+	if opts := notification.Email; opts != nil {
+		if len(opts.CC) > 0 {
+			email.AddCc(opts.CC...)
+		}
+		if len(opts.BCC) > 0 {
+			email.AddBcc(opts.BCC...)
+		}
+
+		// Вложения скачиваются до того, как занять соединение из пула
+		var total int64
+		for _, a := range opts.Attachments {
+			file, err := s.attachment(a)
+			if err != nil {
+				return err
+			}
+			if total += int64(len(file.Data)); s.maxSize > 0 && total > s.maxSize {
+				return fmt.Errorf("email attachments exceed %d bytes", s.maxSize)
+			}
+			email.Attach(file)
+		}
+	}
+
+	if err := email.GetError(); err != nil {
+		return err
+	}
 
-	// Отправляем письмо
-	if err := email.Send(client); err != nil {
+	if err := s.pool.send(email); err != nil {
 		log.Println("Error sending email:", err)
 		return err
 	}
 
-	log.Println("Email sent to", to)
+	log.Println("Email sent to", notification.Recipient)
 	return nil
 }
 
-func (s *EmailSender) Send(notification models.Notification) error {
-	return s.SendEmail(notification.Recipient, notification.Message)
+// Validate проверяет адреса получателя и копий, а также источники вложений
+func (s *EmailSender) Validate(notification models.Notification) error {
+	if _, err := netmail.ParseAddress(notification.Recipient); err != nil {
+		return fmt.Errorf("email recipient %q is not a valid address", notification.Recipient)
+	}
+
+	opts := notification.Email
+	if opts == nil {
+		return nil
+	}
+
+	for _, addr := range append(append([]string{}, opts.CC...), opts.BCC...) {
+		if _, err := netmail.ParseAddress(addr); err != nil {
+			return fmt.Errorf("email copy address %q is not valid", addr)
+		}
+	}
+
+	var total int64
+	for _, a := range opts.Attachments {
+		if strings.TrimSpace(a.Filename) == "" {
+			return errors.New("email attachment filename is required")
+		}
+		if (a.URL == "") == (len(a.Content) == 0) {
+			return fmt.Errorf("email attachment %q must have either url or content", a.Filename)
+		}
+		if a.URL != "" {
+			if _, err := s.attachmentURL(a.URL); err != nil {
+				return fmt.Errorf("email attachment %q: %w", a.Filename, err)
+			}
+		}
+		total += int64(len(a.Content))
+	}
+	if s.maxSize > 0 && total > s.maxSize {
+		return fmt.Errorf("email attachments exceed %d bytes", s.maxSize)
+	}
+
+	return nil
+}
+
+// Close закрывает простаивающие SMTP-соединения
+func (s *EmailSender) Close() error {
+	return s.pool.close()
+}
+
+// attachment возвращает вложение письма, при необходимости скачивая его по URL
+func (s *EmailSender) attachment(a models.Attachment) (*mail.File, error) {
+	file := &mail.File{
+		Name:     a.Filename,
+		MimeType: a.ContentType,
+		Data:     a.Content,
+		Inline:   a.Inline,
+	}
+	if a.URL == "" {
+		return file, nil
+	}
+
+	// Список хостов мог измениться после создания уведомления
+	if _, err := s.attachmentURL(a.URL); err != nil {
+		return nil, err
+	}
+
+	resp, err := s.Client.Get(a.URL)
+	if err != nil {
+		return nil, fmt.Errorf("attachment %s: %w", a.URL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("attachment %s responded %d", a.URL, resp.StatusCode)
+	}
+
+	body := io.Reader(resp.Body)
+	if s.maxSize > 0 {
+		body = io.LimitReader(resp.Body, s.maxSize+1)
+	}
+	file.Data, err = io.ReadAll(body)
+	if err != nil {
+		return nil, fmt.Errorf("attachment %s: %w", a.URL, err)
+	}
+	if s.maxSize > 0 && int64(len(file.Data)) > s.maxSize {
+		return nil, fmt.Errorf("attachment %s exceeds %d bytes", a.URL, s.maxSize)
+	}
+
+	if file.MimeType == "" {
+		file.MimeType = resp.Header.Get("Content-Type")
+	}
+	return file, nil
+}
+
+// attachmentURL проверяет, что вложение можно скачать по адресу raw:
+// абсолютный http(s) URL на хосте из AttachmentHosts
+func (s *EmailSender) attachmentURL(raw string) (*url.URL, error) {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, errors.New("attachment url must be an absolute http(s) URL")
+	}
+	if len(s.hosts) == 0 {
+		return nil, errors.New("attachments by url are disabled, pass content instead")
+	}
+
+	host := strings.ToLower(u.Hostname())
+	for _, h := range s.hosts {
+		h = strings.ToLower(strings.TrimSpace(h))
+		if host == h || (strings.HasPrefix(h, "*.") && strings.HasSuffix(host, h[1:])) {
+			return u, nil
+		}
+	}
+	return nil, fmt.Errorf("attachment host %q is not allowed", u.Hostname())
+}
+
+var (
+	htmlHidden = regexp.MustCompile(`(?is)<(?:head|style|script)\b.*?</(?:head|style|script)>`)
+	htmlBreaks = regexp.MustCompile(`(?i)<br\s*/?>|</(?:p|div|h[1-6]|li|tr|table)>`)
+	htmlTags   = regexp.MustCompile(`<[^>]*>`)
+	blankLines = regexp.MustCompile(`\n{3,}`)
+)
+
+// plainText возвращает текстовую версию HTML-письма: заданную в уведомлении
+// или полученную из HTML удалением разметки
+func plainText(notification models.Notification) string {
+	if notification.Email != nil && notification.Email.Text != "" {
+		return notification.Email.Text
+	}
+
+	text := htmlHidden.ReplaceAllString(notification.Message, "")
+	text = htmlBreaks.ReplaceAllString(text, "\n")
+	text = html.UnescapeString(htmlTags.ReplaceAllString(text, ""))
+
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.Join(strings.Fields(line), " ")
+	}
+	return strings.TrimSpace(blankLines.ReplaceAllString(strings.Join(lines, "\n"), "\n\n"))
 }
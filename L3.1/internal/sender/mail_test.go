@@ -0,0 +1,281 @@
+package sender
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"delayed-notifier/internal/models"
+)
+
+// smtpStandIn — минимальный SMTP-сервер без шифрования и авторизации, как Mailpit:
+// принимает письма и запоминает конверт и содержимое
+type smtpStandIn struct {
+	ln net.Listener
+
+	lock     sync.Mutex
+	conns    int
+	messages []smtpMessage
+}
+
+type smtpMessage struct {
+	from string
+	to   []string
+	data string
+}
+
+func newSMTPStandIn(t *testing.T) *smtpStandIn {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &smtpStandIn{ln: ln}
+	t.Cleanup(func() { _ = ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			s.lock.Lock()
+			s.conns++
+			s.lock.Unlock()
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *smtpStandIn) serve(conn net.Conn) {
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	reply := func(line string) { _, _ = conn.Write([]byte(line + "\r\n")) }
+	reply("220 localhost ESMTP stand-in")
+
+	var msg smtpMessage
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd := strings.TrimRight(line, "\r\n")
+		verb := strings.ToUpper(strings.SplitN(cmd, " ", 2)[0])
+
+		switch verb {
+		case "EHLO", "HELO":
+			reply("250 localhost")
+		case "MAIL":
+			msg = smtpMessage{from: cmd[len("MAIL FROM:"):]}
+			reply("250 OK")
+		case "RCPT":
+			msg.to = append(msg.to, cmd[len("RCPT TO:"):])
+			reply("250 OK")
+		case "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var data strings.Builder
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				data.WriteString(l)
+			}
+			msg.data = data.String()
+			s.lock.Lock()
+			s.messages = append(s.messages, msg)
+			s.lock.Unlock()
+			reply("250 OK queued")
+		case "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			// RSET и NOOP пул отправляет между письмами
+			reply("250 OK")
+		}
+	}
+}
+
+func (s *smtpStandIn) port() int {
+	return s.ln.Addr().(*net.TCPAddr).Port
+}
+
+func (s *smtpStandIn) received() ([]smtpMessage, int) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]smtpMessage(nil), s.messages...), s.conns
+}
+
+func newTestEmailSender(t *testing.T, port int, hosts ...string) *EmailSender {
+	t.Helper()
+
+	s, err := NewEmailSender(EmailConfig{
+		Host:              "127.0.0.1",
+		Port:              port,
+		From:              "notifier@example.com",
+		Encryption:        "none",
+		PoolSize:          1,
+		IdleTimeout:       time.Minute,
+		AttachmentTimeout: time.Second,
+		MaxAttachmentSize: 1 << 20,
+		AttachmentHosts:   hosts,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = s.Close() })
+	return s
+}
+
+func TestEmailSenderDeliversOverSMTP(t *testing.T) {
+	server := newSMTPStandIn(t)
+	s := newTestEmailSender(t, server.port())
+
+	n := models.Notification{
+		Recipient: "alice@example.com",
+		Subject:   "Report",
+		Message:   "<p>Hello <b>Alice</b></p>",
+		HTML:      true,
+		Email: &models.EmailOptions{
+			CC:  []string{"bob@example.com"},
+			BCC: []string{"audit@example.com"},
+			Attachments: []models.Attachment{
+				{Filename: "report.txt", ContentType: "text/plain", Content: []byte("numbers")},
+			},
+		},
+	}
+	if err := s.Validate(n); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	if err := s.Send(n); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if err := s.Send(models.Notification{Recipient: "carol@example.com", Message: "second"}); err != nil {
+		t.Fatalf("second Send: %v", err)
+	}
+
+	messages, conns := server.received()
+	if len(messages) != 2 {
+		t.Fatalf("received %d messages, want 2", len(messages))
+	}
+	if conns != 1 {
+		t.Fatalf("opened %d SMTP connections, want 1 reused from the pool", conns)
+	}
+
+	first := messages[0]
+	rcpt := strings.Join(first.to, " ")
+	for _, addr := range []string{"alice@example.com", "bob@example.com", "audit@example.com"} {
+		if !strings.Contains(rcpt, addr) {
+			t.Errorf("envelope recipients %q miss %s", rcpt, addr)
+		}
+	}
+	for _, want := range []string{"Subject: Report", "text/html", "text/plain", "report.txt"} {
+		if !strings.Contains(first.data, want) {
+			t.Errorf("message does not contain %q", want)
+		}
+	}
+	if strings.Contains(first.data, "audit@example.com") {
+		t.Error("BCC address leaked into message headers")
+	}
+}
+
+func TestEmailAttachmentURLAllowList(t *testing.T) {
+	tests := []struct {
+		name    string
+		hosts   []string
+		url     string
+		wantErr string
+	}{
+		{"disabled without hosts", nil, "https://files.example.com/a.pdf", "disabled"},
+		{"exact host", []string{"files.example.com"}, "https://files.example.com/a.pdf", ""},
+		{"wildcard subdomain", []string{"*.example.com"}, "https://cdn.example.com/a.pdf", ""},
+		{"wildcard does not match apex lookalike", []string{"*.example.com"}, "https://badexample.com/a.pdf", "not allowed"},
+		{"other host", []string{"files.example.com"}, "http://169.254.169.254/latest/meta-data/", "not allowed"},
+		{"non-http scheme", []string{"files.example.com"}, "file:///etc/passwd", "absolute http(s)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestEmailSender(t, 25, tt.hosts...)
+			err := s.Validate(models.Notification{
+				Recipient: "alice@example.com",
+				Email: &models.EmailOptions{
+					Attachments: []models.Attachment{{Filename: "a.pdf", URL: tt.url}},
+				},
+			})
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Validate: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Validate error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestEmailAttachmentRejectsPrivateAddress(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("internal secret"))
+	}))
+	defer srv.Close()
+
+	// Хост разрешён списком, но указывает на петлевой адрес
+	s := newTestEmailSender(t, 25, "127.0.0.1")
+	_, err := s.attachment(models.Attachment{Filename: "a.txt", URL: srv.URL})
+	if !errors.Is(err, errPrivateAddress) {
+		t.Fatalf("attachment error = %v, want errPrivateAddress", err)
+	}
+
+	// С обычным транспортом тот же URL скачивается: проверка именно в момент соединения
+	s.Client.Transport = http.DefaultTransport
+	file, err := s.attachment(models.Attachment{Filename: "a.txt", URL: srv.URL})
+	if err != nil {
+		t.Fatalf("attachment with default transport: %v", err)
+	}
+	if string(file.Data) != "internal secret" {
+		t.Fatalf("unexpected attachment data %q", file.Data)
+	}
+}
+
+func TestCheckPublicAddress(t *testing.T) {
+	tests := []struct {
+		address string
+		public  bool
+	}{
+		{"93.184.216.34:443", true},
+		{"[2606:2800:220:1::]:443", true},
+		{"127.0.0.1:80", false},
+		{"[::1]:80", false},
+		{"[::ffff:127.0.0.1]:80", false},
+		{"10.0.0.5:80", false},
+		{"172.16.0.1:80", false},
+		{"192.168.1.1:80", false},
+		{"169.254.169.254:80", false},
+		{"[fe80::1]:80", false},
+		{"[fd00::1]:80", false},
+		{"100.100.100.200:80", false},
+		{"0.0.0.0:80", false},
+	}
+
+	for _, tt := range tests {
+		err := checkPublicAddress(tt.address)
+		if (err == nil) != tt.public {
+			t.Errorf("checkPublicAddress(%s) = %v, want public %v", tt.address, err, tt.public)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package sender
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// errPrivateAddress — адрес вебхука или вложения указывает во внутреннюю сеть
+var errPrivateAddress = errors.New("address is not public")
+
+// sharedAddressSpace — адреса провайдерского NAT (RFC 6598), не маршрутизируются в интернете
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// newPublicClient возвращает HTTP-клиент для адресов, которые задаёт арендатор.
+// Адрес проверяется при соединении, уже после разрешения имени, поэтому отклоняется
+// и имя, которое указывает на внутренний адрес или сменило DNS-запись после проверки URL.
+func newPublicClient(timeout time.Duration, checkRedirect func(req *http.Request, via []*http.Request) error) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 10 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			return checkPublicAddress(address)
+		},
+	}
+
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			// Прокси из окружения обошёл бы проверку адреса
+			Proxy:               nil,
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: 10 * time.Second,
+		},
+		CheckRedirect: checkRedirect,
+	}
+}
+
+// checkPublicAddress отклоняет петлевые, частные, link-local и прочие немаршрутизируемые адреса
+func checkPublicAddress(address string) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+
+	ip := addrPort.Addr().Unmap()
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() ||
+		ip.IsMulticast() || sharedAddressSpace.Contains(ip) {
+		return fmt.Errorf("%w: %s", errPrivateAddress, ip)
+	}
+	return nil
+}
+
+// checkPublicHost отклоняет заведомо внутренние хосты ещё при создании уведомления:
+// IP-литералы из внутренних сетей и localhost. Имена, которые разрешаются во внутренние
+// адреса, отклоняет клиент из newPublicClient при соединении.
+func checkPublicHost(host string) error {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return fmt.Errorf("%w: %s", errPrivateAddress, host)
+	}
+	if ip, err := netip.ParseAddr(host); err == nil {
+		return checkPublicAddress(netip.AddrPortFrom(ip, 0).String())
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package sender
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"delayed-notifier/internal/models"
+)
+
+// Limiter выдаёт разрешение на отправку; ненулевое ожидание означает, что лимит исчерпан
+type Limiter interface {
+	Reserve(ctx context.Context, n models.Notification) (time.Duration, error)
+}
+
+// ThrottledError возвращается, если отправка упёрлась в лимит.
+// Это не ошибка доставки: уведомление нужно отложить на RetryAfter.
+type ThrottledError struct {
+	RetryAfter time.Duration
+}
+
+func (e *ThrottledError) Error() string {
+	return fmt.Sprintf("rate limited, retry after %s", e.RetryAfter)
+}
+
+// RateLimitedSender ограничивает частоту отправок по каналу и получателю
+type RateLimitedSender struct {
+	next    Sender
+	limiter Limiter
+}
+
+func NewRateLimitedSender(next Sender, limiter Limiter) *RateLimitedSender {
+	return &RateLimitedSender{
+		next:    next,
+		limiter: limiter,
+	}
+}
+
+func (r *RateLimitedSender) Send(notification models.Notification) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	wait, err := r.limiter.Reserve(ctx, notification)
+	if err != nil {
+		// Недоступность Redis не должна останавливать доставку
+		log.Printf("warning: rate limiter unavailable, sending without limit: %v", err)
+	} else if wait > 0 {
+		return &ThrottledError{RetryAfter: wait}
+	}
+
+	return r.next.Send(notification)
+}
+
+// Validate делегирует проверку обёрнутому отправителю
+func (r *RateLimitedSender) Validate(notification models.Notification) error {
+	if v, ok := r.next.(Validator); ok {
+		return v.Validate(notification)
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package sender
+
+import (
+	"log"
+	"sync"
+
+	"delayed-notifier/internal/models"
+)
+
+// RecordingSender запоминает уведомления вместо реальной доставки.
+// Используется для локального запуска и тестов; Fail позволяет имитировать сбои канала.
+type RecordingSender struct {
+	name string
+	// Fail, если задан, вызывается перед записью; ошибка возвращается как ошибка отправки
+	Fail func(notification models.Notification) error
+
+	lock sync.Mutex
+	sent []models.Notification
+}
+
+func NewRecordingSender(name string) *RecordingSender {
+	return &RecordingSender{
+		name: name,
+	}
+}
+
+func (r *RecordingSender) Send(notification models.Notification) error {
+	if r.Fail != nil {
+		if err := r.Fail(notification); err != nil {
+			return err
+		}
+	}
+
+	r.lock.Lock()
+	r.sent = append(r.sent, notification)
+	r.lock.Unlock()
+
+	log.Printf("[%s] recorded notification %s to %q", r.name, notification.ID, notification.Recipient)
+	return nil
+}
+
+// Sent возвращает копию всех записанных уведомлений в порядке отправки
+func (r *RecordingSender) Sent() []models.Notification {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return append([]models.Notification(nil), r.sent...)
+}
+
+// Reset очищает записанные уведомления
+func (r *RecordingSender) Reset() {
+	r.lock.Lock()
+	r.sent = nil
+	r.lock.Unlock()
+}
@@ -1,7 +1,10 @@
 package sender
 
 import (
+	"errors"
 	"fmt"
+	"slices"
+	"strings"
 
 	"delayed-notifier/internal/models"
 )
@@ -10,29 +13,103 @@ type Sender interface {
 	Send(notification models.Notification) error
 }
 
-type MultiSender struct {
-	native   Sender
-	email    Sender
-	telegram Sender
+// Renderer формирует тему и текст уведомления из его шаблона
+type Renderer interface {
+	Render(notification models.Notification) (models.Notification, error)
 }
 
-func NewMultiSender(native Sender, email Sender, telegram Sender) *MultiSender {
-	return &MultiSender{
-		native:   native,
-		email:    email,
-		telegram: telegram,
+// Validator проверяет, что уведомление можно будет отправить, ещё до постановки в очередь
+type Validator interface {
+	Validate(notification models.Notification) error
+}
+
+// ErrUnknownChannel — для канала уведомления не зарегистрирован отправитель
+var ErrUnknownChannel = errors.New("unknown channel")
+
+// Registry выбирает отправителя по имени канала уведомления. Отправители
+// регистрируются при старте со своими настройками и проверкой получателей,
+// поэтому новый канал не требует правок модели и диспетчеризации.
+type Registry struct {
+	renderer Renderer
+	senders  map[models.ChannelType]Sender
+}
+
+func NewRegistry(renderer Renderer) *Registry {
+	return &Registry{
+		renderer: renderer,
+		senders:  make(map[models.ChannelType]Sender),
+	}
+}
+
+// Register добавляет отправителя канала channel. Повторная регистрация
+// имени — ошибка конфигурации.
+func (r *Registry) Register(channel models.ChannelType, sender Sender) error {
+	if _, err := models.ParseChannel(string(channel)); err != nil {
+		return err
+	}
+	if _, ok := r.senders[channel]; ok {
+		return fmt.Errorf("channel %q is already registered", channel)
+	}
+	r.senders[channel] = sender
+	return nil
+}
+
+// Channels возвращает имена зарегистрированных каналов по алфавиту
+func (r *Registry) Channels() []models.ChannelType {
+	channels := make([]models.ChannelType, 0, len(r.senders))
+	for channel := range r.senders {
+		channels = append(channels, channel)
+	}
+	slices.Sort(channels)
+	return channels
+}
+
+func (r *Registry) Send(notification models.Notification) error {
+	target, err := r.senderFor(notification.Channel)
+	if err != nil {
+		return err
+	}
+
+	rendered, err := r.render(notification)
+	if err != nil {
+		return err
+	}
+
+	return target.Send(rendered)
+}
+
+// Validate проверяет канал, получателя и, если задан шаблон, пробно его рендерит
+func (r *Registry) Validate(notification models.Notification) error {
+	target, err := r.senderFor(notification.Channel)
+	if err != nil {
+		return err
+	}
+
+	if v, ok := target.(Validator); ok {
+		if err := v.Validate(notification); err != nil {
+			return err
+		}
+	}
+
+	_, err = r.render(notification)
+	return err
+}
+
+func (r *Registry) senderFor(channel models.ChannelType) (Sender, error) {
+	if target, ok := r.senders[channel]; ok {
+		return target, nil
+	}
+
+	names := make([]string, 0, len(r.senders))
+	for _, c := range r.Channels() {
+		names = append(names, c.String())
 	}
+	return nil, fmt.Errorf("%w %q, available: %s", ErrUnknownChannel, channel, strings.Join(names, ", "))
 }
 
-func (m *MultiSender) Send(notification models.Notification) error {
-	switch notification.Channel {
-	case models.Native:
-		return m.native.Send(notification)
-	case models.Email:
-		return m.email.Send(notification)
-	case models.Telegram:
-		return m.telegram.Send(notification)
-	default:
-		return fmt.Errorf("unknown channel: %v", notification.Channel)
+func (r *Registry) render(notification models.Notification) (models.Notification, error) {
+	if notification.Template == "" || r.renderer == nil {
+		return notification, nil
 	}
+	return r.renderer.Render(notification)
 }
@@ -0,0 +1,116 @@
+package sender
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"time"
+
+	"delayed-notifier/internal/models"
+)
+
+// SMS — канал, под которым регистрируется SMSSender
+const SMS models.ChannelType = "sms"
+
+// SMSConfig — настройки HTTP-шлюза SMS
+type SMSConfig struct {
+	// GatewayURL — адрес, на который POST-запросом отправляется SMSMessage
+	GatewayURL string
+	// Token, если задан, передаётся в заголовке Authorization: Bearer
+	Token string
+	// From — имя или номер отправителя
+	From    string
+	Timeout time.Duration
+}
+
+// SMSMessage — тело запроса к шлюзу. ID повторяется при повторной отправке
+// того же уведомления, по нему шлюз может отбрасывать дубликаты.
+type SMSMessage struct {
+	ID   string `json:"id"`
+	From string `json:"from,omitempty"`
+	To   string `json:"to"`
+	Text string `json:"text"`
+}
+
+// SMSSender отправляет SMS через HTTP-шлюз: любой сервис, принимающий SMSMessage в JSON
+// и отвечающий 2xx на принятое сообщение
+type SMSSender struct {
+	GatewayURL string
+	Token      string
+	From       string
+	Client     *http.Client
+}
+
+// NewSMSSender создает новый объект SMSSender
+func NewSMSSender(cfg SMSConfig) (*SMSSender, error) {
+	u, err := url.Parse(cfg.GatewayURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("sms gateway url must be an absolute http(s) URL, got %q", cfg.GatewayURL)
+	}
+
+	return &SMSSender{
+		GatewayURL: cfg.GatewayURL,
+		Token:      cfg.Token,
+		From:       cfg.From,
+		Client:     &http.Client{Timeout: cfg.Timeout},
+	}, nil
+}
+
+// phoneNumber — номер в формате E.164: "+" и до 15 цифр
+var phoneNumber = regexp.MustCompile(`^\+[1-9][0-9]{6,14}$`)
+
+func (s *SMSSender) Send(notification models.Notification) error {
+	text := notification.Message
+	if notification.HTML {
+		text = plainText(notification)
+	}
+
+	body, err := json.Marshal(SMSMessage{
+		ID:   notification.ID,
+		From: s.From,
+		To:   notification.Recipient,
+		Text: text,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.GatewayURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.Token)
+	}
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("sms gateway: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		// Ошибка сохраняется в last_error и видна арендатору, поэтому ответ
+		// шлюза (в нём бывают данные учётной записи) остаётся только в журнале
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		log.Printf("sms gateway responded %d to notification %s: %s", resp.StatusCode, notification.ID, snippet)
+		return fmt.Errorf("sms gateway responded %d", resp.StatusCode)
+	}
+
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+// Validate проверяет, что получатель — номер телефона в формате E.164
+func (s *SMSSender) Validate(notification models.Notification) error {
+	if !phoneNumber.MatchString(notification.Recipient) {
+		return fmt.Errorf("sms recipient must be a phone number in E.164 format like +79991234567, got %q", notification.Recipient)
+	}
+	return nil
+}
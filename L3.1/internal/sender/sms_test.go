@@ -0,0 +1,138 @@
+package sender
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"delayed-notifier/internal/models"
+)
+
+func newTestSMSSender(t *testing.T, gatewayURL string) *SMSSender {
+	t.Helper()
+
+	s, err := NewSMSSender(SMSConfig{
+		GatewayURL: gatewayURL,
+		Token:      "gateway-token",
+		From:       "Notifier",
+		Timeout:    time.Second,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestSMSSenderPostsMessage(t *testing.T) {
+	var (
+		got  SMSMessage
+		auth string
+		ct   string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		ct = r.Header.Get("Content-Type")
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	s := newTestSMSSender(t, srv.URL)
+	err := s.Send(models.Notification{
+		ID:        "7",
+		Recipient: "+79991234567",
+		Message:   "<p>Code <b>1234</b></p>",
+		HTML:      true,
+	})
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	if auth != "Bearer gateway-token" {
+		t.Errorf("Authorization = %q, want bearer token", auth)
+	}
+	if ct != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", ct)
+	}
+	if got.ID != "7" || got.From != "Notifier" || got.To != "+79991234567" {
+		t.Errorf("unexpected message: %+v", got)
+	}
+	if strings.Contains(got.Text, "<") || !strings.Contains(got.Text, "1234") {
+		t.Errorf("HTML message should be sent as plain text, got %q", got.Text)
+	}
+}
+
+func TestSMSSenderGatewayStatus(t *testing.T) {
+	tests := []struct {
+		status  int
+		wantErr bool
+	}{
+		{http.StatusOK, false},
+		{http.StatusAccepted, false},
+		{http.StatusPaymentRequired, true},
+		{http.StatusTooManyRequests, true},
+		{http.StatusServiceUnavailable, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(strconv.Itoa(tt.status), func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte("account 4411 balance 0.00"))
+			}))
+			defer srv.Close()
+
+			err := newTestSMSSender(t, srv.URL).Send(models.Notification{ID: "1", Recipient: "+79991234567", Message: "hello"})
+			if !tt.wantErr {
+				if err != nil {
+					t.Fatalf("Send: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), strconv.Itoa(tt.status)) {
+				t.Fatalf("Send error = %v, want one naming status %d", err, tt.status)
+			}
+			if strings.Contains(err.Error(), "balance") {
+				t.Fatalf("gateway response leaked into the stored error: %q", err)
+			}
+		})
+	}
+}
+
+func TestSMSSenderValidate(t *testing.T) {
+	s := newTestSMSSender(t, "https://sms.example.com/send")
+
+	tests := []struct {
+		recipient string
+		valid     bool
+	}{
+		{"+79991234567", true},
+		{"+14155552671", true},
+		{"+123456789012345", true},
+		{"79991234567", false},
+		{"+0123456789", false},
+		{"+7 999 123-45-67", false},
+		{"+1234567890123456", false},
+		{"+12345", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		err := s.Validate(models.Notification{Recipient: tt.recipient})
+		if (err == nil) != tt.valid {
+			t.Errorf("Validate(%q) = %v, want valid %v", tt.recipient, err, tt.valid)
+		}
+	}
+}
+
+func TestNewSMSSenderRejectsBadURL(t *testing.T) {
+	for _, gatewayURL := range []string{"", "sms.example.com/send", "ftp://sms.example.com", "https://"} {
+		if _, err := NewSMSSender(SMSConfig{GatewayURL: gatewayURL}); err == nil {
+			t.Errorf("NewSMSSender(%q) should fail", gatewayURL)
+		}
+	}
+}
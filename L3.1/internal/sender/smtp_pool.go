@@ -0,0 +1,116 @@
+package sender
+
+import (
+	"sync"
+	"time"
+
+	mail "github.com/xhit/go-simple-mail/v2"
+)
+
+// smtpPool ограничивает число одновременно открытых SMTP-соединений и
+// переиспользует их между письмами, чтобы всплеск напоминаний не открывал
+// по соединению на каждое. Письмо ждёт, пока освободится место в пуле.
+type smtpPool struct {
+	server      *mail.SMTPServer
+	idleTimeout time.Duration
+	slots       chan struct{}
+
+	lock sync.Mutex
+	idle []*smtpConn
+}
+
+type smtpConn struct {
+	client   *mail.SMTPClient
+	lastUsed time.Time
+}
+
+func newSMTPPool(server *mail.SMTPServer, size int, idleTimeout time.Duration) *smtpPool {
+	if size <= 0 {
+		size = 1
+	}
+
+	return &smtpPool{
+		server:      server,
+		idleTimeout: idleTimeout,
+		slots:       make(chan struct{}, size),
+	}
+}
+
+// send отправляет письмо через свободное соединение. Если переиспользованное
+// соединение оказалось закрыто сервером, письмо повторяется на новом.
+func (p *smtpPool) send(email *mail.Email) error {
+	p.slots <- struct{}{}
+	defer func() { <-p.slots }()
+
+	conn, reused, err := p.get()
+	if err != nil {
+		return err
+	}
+
+	err = email.Send(conn.client)
+	if err != nil && reused {
+		_ = conn.client.Close()
+		if conn, err = p.connect(); err != nil {
+			return err
+		}
+		err = email.Send(conn.client)
+	}
+	if err != nil {
+		// Состояние соединения после ошибки неизвестно — не возвращаем его в пул
+		_ = conn.client.Close()
+		return err
+	}
+
+	p.put(conn)
+	return nil
+}
+
+// get берёт простаивающее соединение или открывает новое.
+// Соединения, простоявшие дольше idleTimeout, закрываются: сервер мог их уже оборвать.
+func (p *smtpPool) get() (*smtpConn, bool, error) {
+	p.lock.Lock()
+	for len(p.idle) > 0 {
+		conn := p.idle[len(p.idle)-1]
+		p.idle = p.idle[:len(p.idle)-1]
+
+		if p.idleTimeout > 0 && time.Since(conn.lastUsed) > p.idleTimeout {
+			_ = conn.client.Close()
+			continue
+		}
+
+		p.lock.Unlock()
+		return conn, true, nil
+	}
+	p.lock.Unlock()
+
+	conn, err := p.connect()
+	return conn, false, err
+}
+
+func (p *smtpPool) connect() (*smtpConn, error) {
+	client, err := p.server.Connect()
+	if err != nil {
+		return nil, err
+	}
+	return &smtpConn{client: client}, nil
+}
+
+func (p *smtpPool) put(conn *smtpConn) {
+	conn.lastUsed = time.Now()
+
+	p.lock.Lock()
+	p.idle = append(p.idle, conn)
+	p.lock.Unlock()
+}
+
+// close закрывает все простаивающие соединения
+func (p *smtpPool) close() error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	for _, conn := range p.idle {
+		_ = conn.client.Quit()
+	}
+	p.idle = nil
+	return nil
+}
@@ -1,36 +1,76 @@
 package sender
 
 import (
+	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"log"
+	"strings"
 	"sync"
+	"time"
 
 	"delayed-notifier/internal/models"
+	"delayed-notifier/internal/repository"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
 
-// TelegramSender хранит бота и зарегистрированных пользователей
+// TelegramSender хранит бота и зарегистрированных пользователей.
+// Регистрации хранятся в БД и при отправке читаются оттуда: /register и /stop
+// могла обработать другая реплика. В памяти держится только индекс chat_id -> username,
+// по которому бот замечает смену username.
 type TelegramSender struct {
 	bot   *tgbotapi.BotAPI
-	users map[string]int64
+	chats repository.TelegramChatRepo
+	names map[int64]string
 	lock  sync.RWMutex
 }
 
-// NewTelegramSender создаёт нового бота
-func NewTelegramSender(token string) (*TelegramSender, error) {
+// ErrTelegramDisabled возвращается при отправке в Telegram, если токен бота не задан
+var ErrTelegramDisabled = errors.New("telegram bot is not configured")
+
+// NewTelegramSender создаёт нового бота и загружает сохранённые регистрации.
+// Без токена бот не запускается: список получателей доступен, а отправка
+// в Telegram отклоняется с ErrTelegramDisabled.
+func NewTelegramSender(token string, chats repository.TelegramChatRepo) (*TelegramSender, error) {
+	t := &TelegramSender{
+		chats: chats,
+		names: make(map[int64]string),
+	}
+
+	if token == "" {
+		log.Println("warning: TG_BOT_TOKEN is empty, telegram channel is disabled")
+		return t, nil
+	}
+
 	bot, err := tgbotapi.NewBotAPI(token)
 	if err != nil {
 		return nil, err
 	}
-	return &TelegramSender{
-		bot:   bot,
-		users: make(map[string]int64),
-	}, nil
+	t.bot = bot
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	registered, err := chats.GetActive(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load telegram chats: %w", err)
+	}
+	for _, chat := range registered {
+		t.remember(chat.ChatID, chat.Username)
+	}
+	log.Printf("Telegram: loaded %d registered users", len(registered))
+
+	return t, nil
 }
 
 // ListenAndServe обрабатывает команды пользователей
 func (t *TelegramSender) ListenAndServe() {
+	if t.bot == nil {
+		return
+	}
+
 	u := tgbotapi.NewUpdate(0)
 	u.Timeout = 60
 
@@ -43,56 +83,168 @@ func (t *TelegramSender) ListenAndServe() {
 
 		if update.Message.IsCommand() {
 			t.handleCommand(update.Message)
+			continue
 		}
+
+		t.trackUsername(update.Message)
 	}
 }
 
-// handleCommand регистрирует пользователя по команде /register
+// handleCommand регистрирует пользователя по команде /register и отписывает по /stop
 func (t *TelegramSender) handleCommand(msg *tgbotapi.Message) {
 	switch msg.Command() {
 	case "register", "start":
 		username := msg.From.UserName
 		chatID := msg.Chat.ID
 
-		t.lock.Lock()
-		t.users[username] = chatID
-		t.lock.Unlock()
+		if username == "" {
+			t.reply(chatID, "Для регистрации задайте username в настройках Telegram и повторите /register")
+			return
+		}
+
+		if err := t.register(msg); err != nil {
+			log.Printf("Failed to register telegram user %q: %v", username, err)
+			t.reply(chatID, "Не удалось зарегистрироваться, попробуйте позже")
+			return
+		}
+
+		t.reply(chatID, "✅ Вы успешно зарегистрированы!\nВаш ID: "+username)
+	case "stop":
+		chatID := msg.Chat.ID
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
 
-		reply := tgbotapi.NewMessage(chatID, "✅ Вы успешно зарегистрированы!\nВаш ID: "+msg.From.UserName)
-		_, err := t.bot.Send(reply)
-		if err != nil {
-			log.Println("Failed to send registration confirmation:", err)
+		if err := t.chats.Deactivate(ctx, chatID); err != nil {
+			log.Printf("Failed to unsubscribe telegram chat %d: %v", chatID, err)
+			t.reply(chatID, "Не удалось отписаться, попробуйте позже")
+			return
 		}
+		t.forget(chatID)
+
+		t.reply(chatID, "Вы отписались от уведомлений. Чтобы подписаться снова, отправьте /register")
 	default:
-		reply := tgbotapi.NewMessage(msg.Chat.ID, "Неизвестная команда")
-		_, _ = t.bot.Send(reply)
+		t.reply(msg.Chat.ID, "Неизвестная команда")
 	}
 }
 
-// SendMessageToUser отправляет сообщение зарегистрированному пользователю по username
-func (t *TelegramSender) SendMessageToUser(username, text string) error {
+// trackUsername обновляет регистрацию, если зарегистрированный пользователь сменил username
+func (t *TelegramSender) trackUsername(msg *tgbotapi.Message) {
+	if msg.From == nil || msg.From.UserName == "" {
+		return
+	}
+
 	t.lock.RLock()
-	chatID, ok := t.users[username]
+	known, ok := t.names[msg.Chat.ID]
 	t.lock.RUnlock()
 
-	if !ok {
-		return fmt.Errorf("user %q not registered, cannot send message", username)
+	if !ok || known == msg.From.UserName {
+		return
 	}
 
-	msg := tgbotapi.NewMessage(chatID, text)
-	_, err := t.bot.Send(msg)
+	if err := t.register(msg); err != nil {
+		log.Printf("Failed to update telegram username %q -> %q: %v", known, msg.From.UserName, err)
+		return
+	}
+	log.Printf("Telegram user %q renamed to %q", known, msg.From.UserName)
+}
+
+func (t *TelegramSender) register(msg *tgbotapi.Message) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	chat := &models.TelegramChat{
+		ChatID:    msg.Chat.ID,
+		Username:  msg.From.UserName,
+		FirstName: msg.From.FirstName,
+	}
+	if err := t.chats.Upsert(ctx, chat); err != nil {
+		return err
+	}
+
+	t.remember(chat.ChatID, chat.Username)
+	return nil
+}
+
+func (t *TelegramSender) remember(chatID int64, username string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.names[chatID] = username
+}
+
+func (t *TelegramSender) forget(chatID int64) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	delete(t.names, chatID)
+}
+
+func (t *TelegramSender) reply(chatID int64, text string) {
+	if _, err := t.bot.Send(tgbotapi.NewMessage(chatID, text)); err != nil {
+		log.Println("Failed to send reply:", err)
+	}
+}
+
+// SendMessageToUser отправляет сообщение зарегистрированному пользователю по username
+func (t *TelegramSender) SendMessageToUser(username, text string) error {
+	username = strings.TrimPrefix(username, "@")
+
+	chatID, err := t.chatID(username)
 	if err != nil {
+		return err
+	}
+
+	msg := tgbotapi.NewMessage(chatID, text)
+	if _, err := t.bot.Send(msg); err != nil {
 		return fmt.Errorf("failed to send Telegram message to %q: %w", username, err)
 	}
 
 	return nil
 }
 
+// chatID ищет действующий чат пользователя в БД. Кэша нет: отписку через
+// /stop на другой реплике он бы не увидел и продолжил слать в отписанный чат.
+func (t *TelegramSender) chatID(username string) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	chat, err := t.chats.GetActiveByUsername(ctx, username)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, fmt.Errorf("user %q not registered, cannot send message", username)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to look up telegram chat of %q: %w", username, err)
+	}
+
+	return chat.ChatID, nil
+}
+
+// Recipients возвращает всех пользователей, когда-либо регистрировавшихся в боте
+func (t *TelegramSender) Recipients(ctx context.Context) ([]*models.TelegramChat, error) {
+	return t.chats.GetAll(ctx)
+}
+
 func (t *TelegramSender) Send(notification models.Notification) error {
+	if t.bot == nil {
+		return ErrTelegramDisabled
+	}
 	return t.SendMessageToUser(notification.Recipient, notification.Message)
 }
 
+// Validate отклоняет уведомления в Telegram, если бот не настроен
+func (t *TelegramSender) Validate(notification models.Notification) error {
+	if t.bot == nil {
+		return ErrTelegramDisabled
+	}
+	return nil
+}
+
 func (t *TelegramSender) Stop() {
+	if t.bot == nil {
+		return
+	}
+
 	log.Println("Stopping Telegram bot...")
 
 	t.bot.StopReceivingUpdates()
@@ -0,0 +1,131 @@
+package sender
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"delayed-notifier/internal/models"
+)
+
+const (
+	// WebhookSignatureHeader содержит "sha256=" + hex(HMAC-SHA256(secret, timestamp + "." + body))
+	WebhookSignatureHeader = "X-Notifier-Signature"
+	// WebhookTimestampHeader содержит время подписи в Unix-секундах; получатель
+	// должен отклонять запросы со слишком старой меткой, чтобы их нельзя было повторить
+	WebhookTimestampHeader = "X-Notifier-Timestamp"
+)
+
+// WebhookPayload — тело запроса, которое получает адрес из Recipient
+type WebhookPayload struct {
+	ID        string           `json:"id"`
+	UserID    string           `json:"user_id"`
+	Subject   string           `json:"subject,omitempty"`
+	Message   string           `json:"message"`
+	SendAt    time.Time        `json:"send_at"`
+	Variables models.Variables `json:"variables,omitempty"`
+}
+
+// WebhookSender отправляет уведомления POST-запросом на URL получателя
+type WebhookSender struct {
+	Secret []byte
+	// Client по умолчанию не соединяется с внутренними адресами и не следует перенаправлениям
+	Client *http.Client
+}
+
+// NewWebhookSender создает новый объект WebhookSender
+func NewWebhookSender(secret string, timeout time.Duration) *WebhookSender {
+	return &WebhookSender{
+		Secret: []byte(secret),
+		// Перенаправление — такой же ответ не 2xx: получатель должен принимать запрос по своему адресу
+		Client: newPublicClient(timeout, func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		}),
+	}
+}
+
+func (s *WebhookSender) Send(notification models.Notification) error {
+	body, err := json.Marshal(WebhookPayload{
+		ID:        notification.ID,
+		UserID:    notification.UserID,
+		Subject:   notification.Subject,
+		Message:   notification.Message,
+		SendAt:    notification.SendAt,
+		Variables: notification.Variables,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, notification.Recipient, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookTimestampHeader, timestamp)
+	req.Header.Set(WebhookSignatureHeader, SignWebhook(s.Secret, timestamp, body))
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook %s: %w", notification.Recipient, err)
+	}
+	defer resp.Body.Close()
+
+	// Тело ответа в ошибку не попадает: она сохраняется в last_error и видна
+	// арендатору, а адрес он выбирает сам
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook %s responded %d", notification.Recipient, resp.StatusCode)
+	}
+	return nil
+}
+
+// Validate проверяет, что получатель — абсолютный http(s) URL не во внутренней сети
+func (s *WebhookSender) Validate(notification models.Notification) error {
+	u, err := url.Parse(notification.Recipient)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("webhook recipient must be an absolute http(s) URL, got %q", notification.Recipient)
+	}
+	if err := checkPublicHost(u.Hostname()); err != nil {
+		return fmt.Errorf("webhook recipient: %w", err)
+	}
+	return nil
+}
+
+// SignWebhook вычисляет значение заголовка X-Notifier-Signature
+func SignWebhook(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyWebhook проверяет подпись и свежесть запроса на стороне получателя
+func VerifyWebhook(secret []byte, timestamp, signature string, body []byte, maxAge time.Duration) error {
+	sec, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return errors.New("invalid webhook timestamp")
+	}
+
+	age := time.Since(time.Unix(sec, 0))
+	if age > maxAge || age < -maxAge {
+		return errors.New("webhook timestamp is outside the allowed window")
+	}
+
+	if !hmac.Equal([]byte(signature), []byte(SignWebhook(secret, timestamp, body))) {
+		return errors.New("invalid webhook signature")
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package sender
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"delayed-notifier/internal/models"
+)
+
+// newLoopbackWebhookSender возвращает отправителя, которому разрешено соединяться
+// с тестовым сервером на 127.0.0.1 — клиент по умолчанию такие адреса отклоняет
+func newLoopbackWebhookSender(secret string, srv *httptest.Server) *WebhookSender {
+	s := NewWebhookSender(secret, time.Second)
+	s.Client = srv.Client()
+	return s
+}
+
+func TestWebhookSenderSignsRequest(t *testing.T) {
+	secret := []byte("top-secret")
+
+	var (
+		got      WebhookPayload
+		verified error
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		verified = VerifyWebhook(secret,
+			r.Header.Get(WebhookTimestampHeader), r.Header.Get(WebhookSignatureHeader), body, time.Minute)
+		_ = json.Unmarshal(body, &got)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	s := newLoopbackWebhookSender(string(secret), srv)
+	err := s.Send(models.Notification{ID: "42", UserID: "u1", Message: "hello", Recipient: srv.URL})
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if verified != nil {
+		t.Fatalf("receiver rejected the signature: %v", verified)
+	}
+	if got.ID != "42" || got.UserID != "u1" || got.Message != "hello" {
+		t.Fatalf("unexpected payload: %+v", got)
+	}
+}
+
+func TestWebhookSenderHidesResponseBody(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"instance-id":"i-0123456789"}`, http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	s := newLoopbackWebhookSender("secret", srv)
+	err := s.Send(models.Notification{ID: "1", Message: "hello", Recipient: srv.URL})
+	if err == nil {
+		t.Fatal("Send succeeded on a 502 response")
+	}
+	if !strings.Contains(err.Error(), "502") {
+		t.Errorf("error %q does not mention the status code", err)
+	}
+	if strings.Contains(err.Error(), "instance-id") {
+		t.Errorf("response body leaked into the stored error: %q", err)
+	}
+}
+
+func TestWebhookSenderRefusesLoopbackTarget(t *testing.T) {
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+	}))
+	defer srv.Close()
+
+	// Клиент по умолчанию: соединение с 127.0.0.1 отклоняется до отправки запроса
+	s := NewWebhookSender("secret", time.Second)
+	err := s.Send(models.Notification{ID: "1", Message: "hello", Recipient: srv.URL})
+	if !errors.Is(err, errPrivateAddress) {
+		t.Fatalf("Send error = %v, want errPrivateAddress", err)
+	}
+	if hits.Load() != 0 {
+		t.Fatal("request reached the loopback server")
+	}
+}
+
+func TestWebhookSenderValidate(t *testing.T) {
+	tests := []struct {
+		recipient string
+		valid     bool
+	}{
+		{"https://hooks.example.com/notify", true},
+		{"http://93.184.216.34/hook", true},
+		{"ftp://hooks.example.com/notify", false},
+		{"/relative/path", false},
+		{"http://localhost:8080/hook", false},
+		{"http://api.localhost/hook", false},
+		{"http://127.0.0.1/hook", false},
+		{"http://10.0.0.7/hook", false},
+		{"http://169.254.169.254/latest/meta-data/", false},
+		{"http://[::1]/hook", false},
+	}
+
+	s := NewWebhookSender("secret", time.Second)
+	for _, tt := range tests {
+		err := s.Validate(models.Notification{Recipient: tt.recipient})
+		if (err == nil) != tt.valid {
+			t.Errorf("Validate(%q) = %v, want valid %v", tt.recipient, err, tt.valid)
+		}
+	}
+}
+
+func TestVerifyWebhook(t *testing.T) {
+	secret := []byte("secret")
+	body := []byte(`{"id":"1"}`)
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	old := strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10)
+
+	tests := []struct {
+		name      string
+		timestamp string
+		signature string
+		body      []byte
+		wantErr   bool
+	}{
+		{"valid", now, SignWebhook(secret, now, body), body, false},
+		{"tampered body", now, SignWebhook(secret, now, body), []byte(`{"id":"2"}`), true},
+		{"wrong secret", now, SignWebhook([]byte("other"), now, body), body, true},
+		{"replayed timestamp", old, SignWebhook(secret, old, body), body, true},
+		{"bad timestamp", "yesterday", SignWebhook(secret, "yesterday", body), body, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := VerifyWebhook(secret, tt.timestamp, tt.signature, tt.body, 5*time.Minute)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("VerifyWebhook() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -6,23 +6,83 @@ import (
 
 	"delayed-notifier/config"
 	"delayed-notifier/internal/handler"
+	"delayed-notifier/internal/models"
 
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 // NewRouter создает Gin-роутер с маршрутами
-func NewRouter(notifHandler *handler.NotificationHandler) *gin.Engine {
+func NewRouter(notifHandler *handler.NotificationHandler, tgHandler *handler.TelegramHandler, tplHandler *handler.TemplateHandler, adminHandler *handler.AdminHandler, eventsHandler *handler.EventsHandler, healthHandler *handler.HealthHandler, authHandler *handler.AuthHandler) *gin.Engine {
 	router := gin.Default()
+	// Арендатор кладётся в контекст запроса; c.Value должен его видеть
+	router.ContextWithFallback = true
 
-	// Роуты уведомлений
+	// Метрики и проверки состояния
+	router.GET("/metrics", gin.WrapH(promhttp.Handler()))
+	router.GET("/healthz", healthHandler.Healthz)
+	router.GET("/readyz", healthHandler.Readyz)
+
+	// Роуты уведомлений: API-ключ арендатора с нужным правом
+	create := authHandler.Require(models.ScopeCreate)
+	read := authHandler.Require(models.ScopeRead)
 	api := router.Group("/notify")
 	{
-		api.POST("", notifHandler.CreateNotification)
-		api.GET("", notifHandler.ListNotifications)
-		api.GET("/:id", notifHandler.GetNotification)
-		api.DELETE("/:id", notifHandler.CancelNotification)
+		api.POST("", create, notifHandler.CreateNotification)
+		api.POST("/batch", create, notifHandler.CreateBatch)
+		api.GET("", read, notifHandler.ListNotifications)
+		api.POST("/events/ticket", read, authHandler.IssueStreamTicket)
+		api.GET("/events", authHandler.RequireStream(), eventsHandler.Stream)
+		api.GET("/:id", read, notifHandler.GetNotification)
+		api.GET("/:id/occurrences", read, notifHandler.ListOccurrences)
+		api.GET("/:id/attempts", read, notifHandler.ListAttempts)
+		api.PATCH("/:id", authHandler.Require(models.ScopeUpdate), notifHandler.UpdateNotification)
+		api.DELETE("/:id", authHandler.Require(models.ScopeCancel), notifHandler.CancelNotification)
+	}
+
+	// Остальные маршруты общие для всех арендаторов — только с административным токеном
+	adminOnly := authHandler.RequireAdmin()
+
+	// Профили пользователей: часовой пояс и тихие часы
+	users := router.Group("/users", adminOnly)
+	{
+		users.GET("/:id/profile", notifHandler.GetProfile)
+		users.PUT("/:id/profile", notifHandler.SaveProfile)
+	}
+
+	// Недоставленные уведомления
+	dlq := router.Group("/dlq", adminOnly)
+	{
+		dlq.GET("", notifHandler.ListDeadLetters)
+		dlq.POST("/replay", notifHandler.ReplayAllDeadLetters)
+		dlq.POST("/:id/replay", notifHandler.ReplayDeadLetter)
+		dlq.DELETE("", notifHandler.PurgeDeadLetters)
+		dlq.DELETE("/:id", notifHandler.DeleteDeadLetter)
 	}
 
+	// Шаблоны сообщений
+	templates := router.Group("/templates", adminOnly)
+	{
+		templates.POST("", tplHandler.CreateTemplate)
+		templates.GET("", tplHandler.ListTemplates)
+		templates.GET("/:id", tplHandler.GetTemplate)
+		templates.PUT("/:id", tplHandler.UpdateTemplate)
+		templates.DELETE("/:id", tplHandler.DeleteTemplate)
+	}
+
+	// Администрирование
+	admin := router.Group("/admin", adminOnly)
+	{
+		admin.GET("/limits", adminHandler.GetLimits)
+		admin.GET("/outbox", adminHandler.GetOutbox)
+		admin.POST("/keys", authHandler.IssueKey)
+		admin.GET("/keys", authHandler.ListKeys)
+		admin.DELETE("/keys/:id", authHandler.RevokeKey)
+	}
+
+	// Получатели Telegram
+	router.GET("/telegram/recipients", adminOnly, tgHandler.ListRecipients)
+
 	router.GET("/", func(c *gin.Context) {
 		c.File("./internal/handler/static/index.html")
 	})
@@ -0,0 +1,102 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"log"
+	"strings"
+
+	"delayed-notifier/internal/models"
+	"delayed-notifier/internal/repository"
+	"delayed-notifier/internal/ticket"
+)
+
+// ErrInvalidAPIKey возвращается для неизвестного или отозванного ключа
+var ErrInvalidAPIKey = errors.New("invalid api key")
+
+const (
+	// apiKeyPrefix отличает ключи сервиса от других секретов (например, в сканерах утечек)
+	apiKeyPrefix = "nk_"
+	// Сколько символов ключа хранится открыто, чтобы его можно было узнать в списке
+	apiKeyVisible = 11
+)
+
+type APIKeyService struct {
+	repo    repository.APIKeyRepo
+	tickets ticket.Store
+}
+
+func NewAPIKeyService(repo repository.APIKeyRepo, tickets ticket.Store) *APIKeyService {
+	return &APIKeyService{
+		repo:    repo,
+		tickets: tickets,
+	}
+}
+
+// Issue выпускает ключ арендатору. Сам ключ попадает только в k.Key
+// возвращённой структуры — в базе хранится его SHA-256.
+func (s *APIKeyService) Issue(ctx context.Context, k *models.APIKey) error {
+	if err := k.Validate(); err != nil {
+		return err
+	}
+
+	secret := make([]byte, 24)
+	if _, err := rand.Read(secret); err != nil {
+		return err
+	}
+	key := apiKeyPrefix + base64.RawURLEncoding.EncodeToString(secret)
+
+	k.Prefix = key[:apiKeyVisible]
+	if err := s.repo.Create(ctx, k, hashAPIKey(key)); err != nil {
+		return err
+	}
+	k.Key = key
+	return nil
+}
+
+// Authenticate возвращает действующий ключ по его значению
+func (s *APIKeyService) Authenticate(ctx context.Context, key string) (*models.APIKey, error) {
+	if !strings.HasPrefix(key, apiKeyPrefix) {
+		return nil, ErrInvalidAPIKey
+	}
+
+	k, err := s.repo.GetByHash(ctx, hashAPIKey(key))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrInvalidAPIKey
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Touch(ctx, k.ID); err != nil {
+		log.Printf("warning: failed to update last use of api key %s: %v", k.ID, err)
+	}
+	return k, nil
+}
+
+// ListKeys возвращает ключи арендатора без их значений
+func (s *APIKeyService) ListKeys(ctx context.Context, tenantID string) ([]*models.APIKey, error) {
+	keys, err := s.repo.List(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if keys == nil {
+		keys = []*models.APIKey{}
+	}
+	return keys, nil
+}
+
+// Revoke отзывает ключ; запросы с ним сразу перестают проходить
+func (s *APIKeyService) Revoke(ctx context.Context, id int) error {
+	return s.repo.Revoke(ctx, id)
+}
+
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"delayed-notifier/internal/events"
+	"delayed-notifier/internal/models"
+)
+
+// MaxBatchSize — наибольшее число уведомлений в одном пакетном запросе
+const MaxBatchSize = 100
+
+// ErrInvalidBatch возвращается, если пакет пуст или слишком велик
+var ErrInvalidBatch = errors.New("invalid batch")
+
+// BatchResult — результат создания одного уведомления из пакета
+type BatchResult struct {
+	Index        int                  `json:"index"`
+	Created      bool                 `json:"created"`
+	Notification *models.Notification `json:"notification,omitempty"`
+	Error        string               `json:"error,omitempty"`
+}
+
+// CreateBatch создаёт уведомления одной транзакцией вместе с сообщениями для очереди.
+// Уведомления, не прошедшие проверку, пропускаются с ошибкой в результате.
+func (s *NotificationService) CreateBatch(ctx context.Context, items []*models.Notification) ([]BatchResult, error) {
+	if len(items) == 0 {
+		return nil, fmt.Errorf("%w: no notifications", ErrInvalidBatch)
+	}
+	if len(items) > MaxBatchSize {
+		return nil, fmt.Errorf("%w: at most %d notifications allowed", ErrInvalidBatch, MaxBatchSize)
+	}
+
+	results := make([]BatchResult, len(items))
+	var (
+		valid   []*models.Notification
+		indexes []int
+	)
+	for i, n := range items {
+		results[i].Index = i
+		if n == nil {
+			results[i].Error = "notification is required"
+			continue
+		}
+
+		found, err := s.findByIdempotencyKey(ctx, n)
+		if err != nil {
+			return nil, err
+		}
+		if found {
+			results[i].Notification = n
+			continue
+		}
+
+		if err := s.prepare(ctx, n); err != nil {
+			results[i].Error = err.Error()
+			continue
+		}
+		valid = append(valid, n)
+		indexes = append(indexes, i)
+	}
+
+	if len(valid) == 0 {
+		return results, nil
+	}
+
+	created, err := s.repo.CreateBatch(ctx, valid)
+	if err != nil {
+		return nil, err
+	}
+
+	for j, n := range valid {
+		i := indexes[j]
+		results[i].Notification = n
+		results[i].Created = created[j]
+
+		if created[j] {
+			if err := s.cache.Set(ctx, n); err != nil {
+				log.Printf("warning: failed to set notification %s in cache: %v", n.ID, err)
+			}
+			s.emit(ctx, events.NewEvent(events.Created, n))
+		}
+	}
+
+	return results, nil
+}
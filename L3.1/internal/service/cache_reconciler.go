@@ -0,0 +1,151 @@
+package service
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"time"
+
+	"delayed-notifier/internal/cache"
+	"delayed-notifier/internal/metrics"
+	"delayed-notifier/internal/models"
+	"delayed-notifier/internal/repository"
+)
+
+// CacheReconcilerConfig — параметры сверки кэша с Postgres
+type CacheReconcilerConfig struct {
+	Interval  time.Duration
+	BatchSize int
+}
+
+// CacheReconciler периодически сверяет кэш уведомлений с Postgres и чинит расхождения:
+// дописывает запланированные уведомления, вытесненные из кэша, и перезаписывает
+// устаревшие записи, например отмену, которую не удалось отразить в кэше.
+type CacheReconciler struct {
+	repo  repository.NotificationRepo
+	cache cache.NotifCache
+	cfg   CacheReconcilerConfig
+
+	// Начало предыдущего прохода: изменённые после него уведомления проверяются снова
+	lastRun time.Time
+}
+
+// firstRunLookback — за какой срок проверяются изменённые уведомления при первом проходе
+const firstRunLookback = 24 * time.Hour
+
+func NewCacheReconciler(repo repository.NotificationRepo, cache cache.NotifCache, cfg CacheReconcilerConfig) *CacheReconciler {
+	if cfg.Interval <= 0 {
+		cfg.Interval = 5 * time.Minute
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 500
+	}
+
+	return &CacheReconciler{
+		repo:  repo,
+		cache: cache,
+		cfg:   cfg,
+	}
+}
+
+// Run сверяет кэш сразу и затем раз в Interval, пока не отменён ctx
+func (r *CacheReconciler) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := r.Reconcile(ctx); err != nil {
+			metrics.CacheReconcileRuns.WithLabelValues("error").Inc()
+			log.Printf("cache reconcile failed: %v", err)
+		} else {
+			metrics.CacheReconcileRuns.WithLabelValues("ok").Inc()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Reconcile проходит пачками по уведомлениям, которые должны совпадать с кэшем
+func (r *CacheReconciler) Reconcile(ctx context.Context) error {
+	started := time.Now()
+	since := r.lastRun
+	if since.IsZero() {
+		since = started.Add(-firstRunLookback)
+	}
+
+	var checked, repaired, afterID int
+	for ctx.Err() == nil {
+		batch, err := r.repo.GetForReconcile(ctx, since, afterID, r.cfg.BatchSize)
+		if err != nil {
+			return err
+		}
+		if len(batch) == 0 {
+			break
+		}
+
+		ids := make([]int, 0, len(batch))
+		for _, n := range batch {
+			id, err := strconv.Atoi(n.ID)
+			if err != nil {
+				return err
+			}
+			ids = append(ids, id)
+		}
+		cached, err := r.cache.GetMany(ctx, ids)
+		if err != nil {
+			return err
+		}
+
+		for i, n := range batch {
+			if repairCache(ctx, r.cache, cached[ids[i]], n, "reconciler") {
+				repaired++
+			}
+		}
+
+		checked += len(batch)
+		afterID = ids[len(ids)-1]
+		if len(batch) < r.cfg.BatchSize {
+			break
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	// Сдвигаем окно с запасом: изменения, закоммиченные во время прохода, попадут в следующий
+	r.lastRun = started.Add(-time.Minute)
+	if repaired > 0 {
+		log.Printf("cache reconcile: checked %d notifications, repaired %d", checked, repaired)
+	}
+	return nil
+}
+
+// repairCache сравнивает запись кэша с состоянием из Postgres и при расхождении
+// перезаписывает её. Возвращает true, если запись пришлось чинить.
+func repairCache(ctx context.Context, c cache.NotifCache, cached, actual *models.Notification, source string) bool {
+	var kind string
+	switch {
+	case cached == nil && actual.Status == models.Scheduled:
+		kind = "missing"
+	case cached == nil:
+		// Завершённые уведомления в кэше не обязательны
+		return false
+	case cached.Status != actual.Status || cached.Version != actual.Version ||
+		cached.TargetIndex != actual.TargetIndex ||
+		// Postgres хранит время с точностью до микросекунд
+		!cached.SendAt.Truncate(time.Microsecond).Equal(actual.SendAt.Truncate(time.Microsecond)):
+		kind = "stale"
+	default:
+		return false
+	}
+
+	metrics.CacheMismatches.WithLabelValues(kind, source).Inc()
+	if err := c.Set(ctx, actual); err != nil {
+		log.Printf("warning: failed to repair notification %s in cache: %v", actual.ID, err)
+	}
+	return true
+}
@@ -0,0 +1,173 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"delayed-notifier/internal/events"
+	"delayed-notifier/internal/models"
+)
+
+// StoreDeadLetter сохраняет сообщение из DLQ брокера и помечает уведомление как Failed.
+// Уже доставленное или отменённое уведомление статус не меняет: обработчик мог
+// упасть после отправки, например при планировании следующего срабатывания серии.
+func (s *NotificationService) StoreDeadLetter(ctx context.Context, body []byte, reason string) error {
+	d := &models.DeadLetter{
+		Reason:  reason,
+		Payload: string(body),
+	}
+
+	var notif models.Notification
+	if err := json.Unmarshal(body, &notif); err == nil && notif.ID != "" {
+		d.NotificationID = &notif.ID
+	}
+
+	if err := s.deadLetters.Add(ctx, d); err != nil {
+		return err
+	}
+
+	if d.NotificationID != nil {
+		if id, err := strconv.Atoi(notif.ID); err == nil {
+			if _, err := s.transitionStatus(ctx, id, models.Scheduled, models.Failed); err != nil {
+				log.Printf("warning: failed to mark dead-lettered notification %d as failed: %v", id, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// addDeadLetter записывает уведомление, исчерпавшее попытки отправки
+func (s *NotificationService) addDeadLetter(ctx context.Context, notif *models.Notification, reason string) {
+	body, err := json.Marshal(notif)
+	if err != nil {
+		log.Printf("failed to serialize dead letter for notification %s: %v", notif.ID, err)
+		return
+	}
+
+	d := &models.DeadLetter{
+		NotificationID: &notif.ID,
+		Reason:         reason,
+		Payload:        string(body),
+	}
+	if err := s.deadLetters.Add(ctx, d); err != nil {
+		log.Printf("failed to store dead letter for notification %s: %v", notif.ID, err)
+	}
+}
+
+// transitionStatus переводит уведомление из статуса from в to и возвращает его
+// с новым статусом; nil, если уведомление уже не в статусе from
+func (s *NotificationService) transitionStatus(ctx context.Context, id int, from, to models.StatusType) (*models.Notification, error) {
+	ok, err := s.repo.TransitionStatus(ctx, id, from, to)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	notif, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.cache.Set(ctx, notif); err != nil {
+		log.Printf("warning: failed to update notification %d in cache: %v", id, err)
+	}
+
+	s.emit(ctx, events.NewEvent(statusEvent(to), notif))
+	return notif, nil
+}
+
+func (s *NotificationService) ListDeadLetters(ctx context.Context) ([]*models.DeadLetter, error) {
+	return s.deadLetters.GetAll(ctx)
+}
+
+// ReplayDeadLetter сбрасывает счётчик попыток, канал и статус уведомления
+// и снова публикует его для немедленной отправки. Повторяются только уведомления
+// в статусе Failed: отправленное или ещё запланированное было бы доставлено дважды.
+func (s *NotificationService) ReplayDeadLetter(ctx context.Context, id int) error {
+	d, err := s.deadLetters.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if d.NotificationID == nil {
+		return fmt.Errorf("dead letter %d is not linked to a notification", id)
+	}
+
+	notifID, err := strconv.Atoi(*d.NotificationID)
+	if err != nil {
+		return err
+	}
+
+	// Переход из Failed заодно не даёт двум одновременным повторам опубликовать уведомление дважды
+	notif, err := s.transitionStatus(ctx, notifID, models.Failed, models.Scheduled)
+	if err != nil {
+		return err
+	}
+	if notif == nil {
+		return fmt.Errorf("notification %d: %w", notifID, ErrNotFailed)
+	}
+
+	// Повтор начинается заново — с первого канала в порядке предпочтений получателя
+	first := s.firstTarget(ctx, notif)
+	if err := s.repo.SwitchTarget(ctx, notifID, first); err != nil {
+		return err
+	}
+	if err := s.repo.UpdateRetryCount(ctx, notifID, 0); err != nil {
+		return err
+	}
+	notif.Retry = 0
+	notif.TargetIndex = first
+
+	if err := s.republish(ctx, notifID, notif, time.Now()); err != nil {
+		log.Printf("failed to publish message: %v", err)
+		return errors.New("failed to enqueue notification")
+	}
+
+	return s.deadLetters.Delete(ctx, id)
+}
+
+// replayBatch — сколько записей DLQ читается за один запрос при повторе всех
+const replayBatch = 100
+
+// ReplayAllDeadLetters повторяет все недоставленные уведомления и возвращает число успешных.
+// Записи, попавшие в DLQ уже во время прохода (повтор снова не удался), не трогаются.
+func (s *NotificationService) ReplayAllDeadLetters(ctx context.Context) (int, error) {
+	started := time.Now()
+
+	replayed, afterID := 0, 0
+	for {
+		list, err := s.deadLetters.GetBatch(ctx, afterID, started, replayBatch)
+		if err != nil {
+			return replayed, err
+		}
+
+		for _, d := range list {
+			id, err := strconv.Atoi(d.ID)
+			if err != nil {
+				return replayed, err
+			}
+			afterID = id
+
+			if err := s.ReplayDeadLetter(ctx, id); err != nil {
+				log.Printf("failed to replay dead letter %d: %v", id, err)
+				continue
+			}
+			replayed++
+		}
+
+		if len(list) < replayBatch {
+			return replayed, nil
+		}
+	}
+}
+
+func (s *NotificationService) DeleteDeadLetter(ctx context.Context, id int) error {
+	return s.deadLetters.Delete(ctx, id)
+}
+
+func (s *NotificationService) PurgeDeadLetters(ctx context.Context) (int64, error) {
+	return s.deadLetters.DeleteAll(ctx)
+}
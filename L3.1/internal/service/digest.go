@@ -0,0 +1,110 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sort"
+	"time"
+
+	"delayed-notifier/internal/events"
+	"delayed-notifier/internal/metrics"
+	"delayed-notifier/internal/models"
+	"delayed-notifier/internal/sender"
+)
+
+const (
+	// maxDigestSize — сколько уведомлений попадает в один дайджест
+	maxDigestSize = 50
+	// digestLease — через сколько неотправленный дайджест считается брошенным
+	// (воркер упал), и его уведомления можно собрать заново
+	digestLease = 5 * time.Minute
+	// digestRecheck — через сколько проверить уведомление, собранное чужим дайджестом
+	digestRecheck = 30 * time.Second
+)
+
+// errDigestBusy — уведомление уже собрано в дайджест другим воркером
+var errDigestBusy = errors.New("notification is collected into another digest")
+
+// collectDigest собирает дайджест вокруг notif, если пользователь включил его в профиле:
+// запланированные уведомления тому же получателю тем же каналом, время отправки
+// которых не позже notif.SendAt + окно. Возвращает 0, если notif отправляется отдельно.
+func (s *NotificationService) collectDigest(ctx context.Context, notif *models.Notification) (int, []*models.Notification, error) {
+	// Резервные каналы, письма с вложениями и копиями и срочные уведомления в дайджест не собираются
+	if _, ok := s.sender.(sender.Digester); !ok || notif.TargetIndex != 0 || notif.Email != nil ||
+		notif.Priority >= models.PriorityHigh {
+		return 0, nil, nil
+	}
+
+	window := s.digestWindow(ctx, notif)
+	if window <= 0 {
+		return 0, nil, nil
+	}
+
+	digestID, members, err := s.repo.ClaimDigest(ctx, notif, notif.SendAt.Add(window), maxDigestSize, digestLease)
+	if err != nil {
+		return 0, nil, err
+	}
+	if digestID == 0 {
+		for _, m := range members {
+			if m.ID == notif.ID {
+				// Собирать не с чем
+				return 0, nil, nil
+			}
+		}
+		return 0, nil, errDigestBusy
+	}
+
+	sort.Slice(members, func(i, j int) bool {
+		return members[i].SendAt.Before(members[j].SendAt)
+	})
+	return digestID, members, nil
+}
+
+// digestMessage объединяет уведомления дайджеста в одно сообщение от имени notif
+func (s *NotificationService) digestMessage(notif *models.Notification, members []*models.Notification) (models.Notification, error) {
+	batch := make([]models.Notification, len(members))
+	for i, m := range members {
+		batch[i] = *m
+	}
+
+	digest, err := s.sender.(sender.Digester).Digest(batch)
+	if err != nil {
+		return models.Notification{}, err
+	}
+	// Попытка отправки записывается в журнал уведомления, которое собрало дайджест
+	digest.ID = notif.ID
+	return digest, nil
+}
+
+// releaseDigest распускает неотправленный дайджест; его уведомления
+// отправятся по отдельности, каждое в своё время
+func (s *NotificationService) releaseDigest(ctx context.Context, digestID int) {
+	if err := s.repo.ReleaseDigest(ctx, digestID); err != nil {
+		log.Printf("warning: failed to release digest %d: %v", digestID, err)
+	}
+}
+
+// markDigestSent помечает все уведомления дайджеста отправленными и планирует
+// следующие срабатывания повторяющихся
+func (s *NotificationService) markDigestSent(ctx context.Context, digestID int, digest *models.Notification) error {
+	members, err := s.repo.MarkDigestSent(ctx, digestID, digest.Message, digest.Channel)
+	if err != nil {
+		return err
+	}
+	log.Printf("digest %d: %d notifications sent to %s", digestID, len(members), digest.Recipient)
+
+	var errs []error
+	for _, m := range members {
+		metrics.SchedulingLag.WithLabelValues(digest.Channel.String()).Observe(time.Since(m.SendAt).Seconds())
+		if err := s.cache.Set(ctx, m); err != nil {
+			log.Printf("warning: failed to update notification %s in cache: %v", m.ID, err)
+		}
+		s.emit(ctx, events.NewEvent(events.Sent, m))
+
+		if err := s.scheduleNextOccurrence(ctx, m); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
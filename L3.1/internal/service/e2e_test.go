@@ -0,0 +1,554 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"delayed-notifier/internal/cache"
+	"delayed-notifier/internal/events"
+	"delayed-notifier/internal/models"
+	"delayed-notifier/internal/queue"
+	"delayed-notifier/internal/repository"
+	"delayed-notifier/internal/sender"
+
+	"github.com/wb-go/wbf/retry"
+)
+
+// Сквозные тесты: сервис работает с планировщиком, кэшем и отправителем из памяти,
+// а вместо Postgres — memoryRepo с той же семантикой outbox
+
+const (
+	testChannel     models.ChannelType = "test"
+	fallbackChannel models.ChannelType = "fallback"
+)
+
+// memoryRepo хранит уведомления и outbox в памяти. Реализует только методы,
+// через которые проходят создание, отправка, повтор и отмена; вызов остальных паникует.
+type memoryRepo struct {
+	repository.NotificationRepo
+	repository.OutboxRepo
+
+	lock   sync.Mutex
+	nextID int
+	notifs map[int]*models.Notification
+	outbox []*models.OutboxMessage
+}
+
+func newMemoryRepo() *memoryRepo {
+	return &memoryRepo{notifs: make(map[int]*models.Notification)}
+}
+
+func (r *memoryRepo) Create(ctx context.Context, n *models.Notification) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.nextID++
+	n.ID = strconv.Itoa(r.nextID)
+	n.SeriesID = n.ID
+	n.Status = models.Scheduled
+	n.Version = 1
+	n.CreatedAt = time.Now()
+	n.UpdatedAt = n.CreatedAt
+
+	c := *n
+	r.notifs[r.nextID] = &c
+	return r.addOutbox(n)
+}
+
+func (r *memoryRepo) Update(ctx context.Context, id int, patch models.NotificationPatch) (*models.Notification, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	n, ok := r.notifs[id]
+	if !ok || n.Status != models.Scheduled {
+		return nil, sql.ErrNoRows
+	}
+	if patch.SendAt != nil {
+		n.SendAt = *patch.SendAt
+	}
+	if patch.Message != nil {
+		n.Message = *patch.Message
+	}
+	if patch.Recipient != nil {
+		n.Recipient = *patch.Recipient
+	}
+	n.Version++
+	n.UpdatedAt = time.Now()
+
+	if err := r.addOutbox(n); err != nil {
+		return nil, err
+	}
+	c := *n
+	return &c, nil
+}
+
+// addOutbox кладёт текущую версию уведомления в outbox; вызывается под r.lock
+func (r *memoryRepo) addOutbox(n *models.Notification) error {
+	payload, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+	r.outbox = append(r.outbox, &models.OutboxMessage{
+		ID:             strconv.Itoa(len(r.outbox) + 1),
+		NotificationID: n.ID,
+		Payload:        string(payload),
+		PublishAt:      n.SendAt,
+	})
+	return nil
+}
+
+func (r *memoryRepo) GetByID(ctx context.Context, id int) (*models.Notification, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	n, ok := r.notifs[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	c := *n
+	return &c, nil
+}
+
+func (r *memoryRepo) update(id int, fn func(n *models.Notification)) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	n, ok := r.notifs[id]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	fn(n)
+	n.UpdatedAt = time.Now()
+	return nil
+}
+
+func (r *memoryRepo) UpdateStatus(ctx context.Context, id int, status models.StatusType) error {
+	return r.update(id, func(n *models.Notification) { n.Status = status })
+}
+
+func (r *memoryRepo) TransitionStatus(ctx context.Context, id int, from, to models.StatusType) (bool, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	n, ok := r.notifs[id]
+	if !ok || n.Status != from {
+		return false, nil
+	}
+	n.Status = to
+	n.UpdatedAt = time.Now()
+	return true, nil
+}
+
+func (r *memoryRepo) SwitchTarget(ctx context.Context, id int, index int) error {
+	return r.update(id, func(n *models.Notification) {
+		n.TargetIndex = index
+		n.Retry = 0
+	})
+}
+
+func (r *memoryRepo) UpdateRetryCount(ctx context.Context, id int, retryCount int) error {
+	return r.update(id, func(n *models.Notification) { n.Retry = retryCount })
+}
+
+func (r *memoryRepo) MarkDelivered(ctx context.Context, id int, status models.StatusType, channel models.ChannelType) error {
+	return r.update(id, func(n *models.Notification) {
+		n.Status = status
+		n.DeliveredChannel = &channel
+	})
+}
+
+func (r *memoryRepo) MarkQueued(ctx context.Context, id int, at time.Time) error {
+	return r.update(id, func(n *models.Notification) {})
+}
+
+func (r *memoryRepo) AddAttempt(ctx context.Context, a *models.Attempt) error {
+	return nil
+}
+
+func (r *memoryRepo) CancelSeries(ctx context.Context, seriesID int) ([]int, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	var canceled []int
+	for id, n := range r.notifs {
+		if n.SeriesID == strconv.Itoa(seriesID) && n.Status == models.Scheduled {
+			n.Status = models.Canceled
+			canceled = append(canceled, id)
+		}
+	}
+	return canceled, nil
+}
+
+func (r *memoryRepo) Relay(ctx context.Context, limit int, publish func(m *models.OutboxMessage) error, retryDelay func(attempts int) time.Duration) (int, int, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	published, failed := 0, 0
+	for _, m := range r.outbox {
+		if m.PublishedAt != nil || published+failed == limit {
+			continue
+		}
+		if err := publish(m); err != nil {
+			m.Attempts++
+			failed++
+			continue
+		}
+		now := time.Now()
+		m.PublishedAt = &now
+		published++
+	}
+	return published, failed, nil
+}
+
+type noProfiles struct{}
+
+func (noProfiles) Get(ctx context.Context, tenantID, userID string) (*models.UserProfile, error) {
+	return nil, sql.ErrNoRows
+}
+
+func (noProfiles) Upsert(ctx context.Context, p *models.UserProfile) error {
+	return nil
+}
+
+// preferring — профиль любого получателя с заданными предпочитаемыми каналами
+type preferring []models.ChannelType
+
+func (p preferring) Get(ctx context.Context, tenantID, userID string) (*models.UserProfile, error) {
+	return &models.UserProfile{TenantID: tenantID, UserID: userID, PreferredChannels: p}, nil
+}
+
+func (preferring) Upsert(ctx context.Context, p *models.UserProfile) error {
+	return nil
+}
+
+type memoryDeadLetters struct {
+	repository.DeadLetterRepo
+
+	lock  sync.Mutex
+	added []*models.DeadLetter
+}
+
+func (d *memoryDeadLetters) Add(ctx context.Context, dl *models.DeadLetter) error {
+	d.lock.Lock()
+	d.added = append(d.added, dl)
+	dl.ID = strconv.Itoa(len(d.added))
+	d.lock.Unlock()
+	return nil
+}
+
+func (d *memoryDeadLetters) count() int {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	return len(d.added)
+}
+
+func (d *memoryDeadLetters) GetByID(ctx context.Context, id int) (*models.DeadLetter, error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if id < 1 || id > len(d.added) {
+		return nil, sql.ErrNoRows
+	}
+	return d.added[id-1], nil
+}
+
+func (d *memoryDeadLetters) Delete(ctx context.Context, id int) error {
+	return nil
+}
+
+type testEnv struct {
+	svc         *NotificationService
+	relay       *OutboxRelay
+	repo        *memoryRepo
+	deadLetters *memoryDeadLetters
+	sent        *sender.RecordingSender
+	fallback    *sender.RecordingSender
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	return newTestEnvWithProfiles(t, noProfiles{})
+}
+
+func newTestEnvWithProfiles(t *testing.T, profiles repository.UserProfileRepo) *testEnv {
+	t.Helper()
+
+	repo := newMemoryRepo()
+	notifCache := cache.NewMemoryCache()
+	scheduler := queue.NewMemoryScheduler(2)
+	recording := sender.NewRecordingSender(testChannel.String())
+	fallback := sender.NewRecordingSender(fallbackChannel.String())
+
+	registry := sender.NewRegistry(nil)
+	if err := registry.Register(testChannel, recording); err != nil {
+		t.Fatal(err)
+	}
+	if err := registry.Register(fallbackChannel, fallback); err != nil {
+		t.Fatal(err)
+	}
+
+	deadLetters := &memoryDeadLetters{}
+	svc := NewNotificationService(repo, deadLetters, profiles, notifCache, scheduler, registry,
+		retry.Strategy{Attempts: 3, Delay: 20 * time.Millisecond, Backoff: 1}, events.NewMemoryBroker())
+
+	if err := scheduler.Consume(func(ctx context.Context, n models.Notification) error {
+		return svc.ProcessNotification(ctx, &n)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = scheduler.Close() })
+
+	return &testEnv{
+		svc:         svc,
+		relay:       NewOutboxRelay(repo, scheduler, notifCache, OutboxRelayConfig{}),
+		repo:        repo,
+		deadLetters: deadLetters,
+		sent:        recording,
+		fallback:    fallback,
+	}
+}
+
+// schedule создаёт уведомление с отправкой через delay и публикует его из outbox
+func (e *testEnv) schedule(t *testing.T, delay time.Duration, fallbacks ...models.Target) int {
+	t.Helper()
+	ctx := context.Background()
+
+	n := &models.Notification{
+		UserID:    "1",
+		Channel:   testChannel,
+		Recipient: "alice",
+		Message:   "hello",
+		SendAt:    time.Now().Add(delay),
+		Fallbacks: fallbacks,
+	}
+	if _, err := e.svc.CreateNotification(ctx, n); err != nil {
+		t.Fatalf("CreateNotification: %v", err)
+	}
+	e.relay.flush(ctx)
+
+	id, err := strconv.Atoi(n.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+func (e *testEnv) status(t *testing.T, id int) *models.Notification {
+	t.Helper()
+
+	n, err := e.repo.GetByID(context.Background(), id)
+	if err != nil {
+		t.Fatalf("GetByID(%d): %v", id, err)
+	}
+	return n
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(3 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestScheduledNotificationIsSent(t *testing.T) {
+	env := newTestEnv(t)
+	id := env.schedule(t, 100*time.Millisecond)
+
+	if got := len(env.sent.Sent()); got != 0 {
+		t.Fatalf("notification sent before its time: %d sends", got)
+	}
+
+	waitFor(t, "notification to be sent", func() bool {
+		return env.status(t, id).Status == models.Sent
+	})
+
+	sent := env.sent.Sent()
+	if len(sent) != 1 || sent[0].Message != "hello" || sent[0].Recipient != "alice" {
+		t.Fatalf("unexpected sends: %+v", sent)
+	}
+}
+
+func TestCanceledNotificationIsNotSent(t *testing.T) {
+	env := newTestEnv(t)
+	id := env.schedule(t, 150*time.Millisecond)
+
+	if err := env.svc.CancelNotification(context.Background(), id); err != nil {
+		t.Fatalf("CancelNotification: %v", err)
+	}
+
+	// Сообщение остаётся в планировщике и должно быть отброшено при срабатывании
+	time.Sleep(400 * time.Millisecond)
+
+	if got := env.status(t, id).Status; got != models.Canceled {
+		t.Fatalf("status = %d, want canceled", got)
+	}
+	if sent := env.sent.Sent(); len(sent) != 0 {
+		t.Fatalf("canceled notification was sent: %+v", sent)
+	}
+}
+
+func TestFailedSendIsRetried(t *testing.T) {
+	env := newTestEnv(t)
+
+	var calls atomic.Int32
+	env.sent.Fail = func(models.Notification) error {
+		if calls.Add(1) <= 2 {
+			return errors.New("channel is down")
+		}
+		return nil
+	}
+
+	id := env.schedule(t, 50*time.Millisecond)
+
+	waitFor(t, "notification to be sent after retries", func() bool {
+		return env.status(t, id).Status == models.Sent
+	})
+
+	n := env.status(t, id)
+	if n.Retry != 2 {
+		t.Fatalf("retry = %d, want 2", n.Retry)
+	}
+	if got := calls.Load(); got != 3 {
+		t.Fatalf("send attempts = %d, want 3", got)
+	}
+	if sent := env.sent.Sent(); len(sent) != 1 {
+		t.Fatalf("sends = %d, want 1", len(sent))
+	}
+}
+
+func TestDeadLetterDoesNotFailDeliveredNotification(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	id := env.schedule(t, 50*time.Millisecond)
+	waitFor(t, "notification to be sent", func() bool {
+		return env.status(t, id).Status == models.Sent
+	})
+
+	// Обработчик упал уже после отправки, и брокер переложил сообщение в DLQ
+	body, err := json.Marshal(env.status(t, id))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.svc.StoreDeadLetter(ctx, body, "rejected in notifications.main.v2"); err != nil {
+		t.Fatalf("StoreDeadLetter: %v", err)
+	}
+	if got := env.status(t, id).Status; got != models.Sent {
+		t.Fatalf("status = %d, want sent to be kept", got)
+	}
+
+	if err := env.svc.ReplayDeadLetter(ctx, 1); !errors.Is(err, ErrNotFailed) {
+		t.Fatalf("ReplayDeadLetter error = %v, want ErrNotFailed", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if sent := env.sent.Sent(); len(sent) != 1 {
+		t.Fatalf("sends = %d, want 1", len(sent))
+	}
+}
+
+func TestFailedDeadLetterIsReplayed(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	var down atomic.Bool
+	down.Store(true)
+	env.sent.Fail = func(models.Notification) error {
+		if down.Load() {
+			return errors.New("channel is down")
+		}
+		return nil
+	}
+
+	// Попытки исчерпаны — уведомление Failed и записано в DLQ
+	id := env.schedule(t, 50*time.Millisecond)
+	waitFor(t, "notification to fail", func() bool {
+		return env.status(t, id).Status == models.Failed && env.deadLetters.count() == 1
+	})
+
+	down.Store(false)
+	if err := env.svc.ReplayDeadLetter(ctx, 1); err != nil {
+		t.Fatalf("ReplayDeadLetter: %v", err)
+	}
+	waitFor(t, "replayed notification to be sent", func() bool {
+		return env.status(t, id).Status == models.Sent
+	})
+	if n := env.status(t, id); n.Retry != 0 {
+		t.Fatalf("retry = %d, want reset to 0", n.Retry)
+	}
+}
+
+func TestPreferredFallbackIsReportedAsFallback(t *testing.T) {
+	env := newTestEnvWithProfiles(t, preferring{fallbackChannel})
+
+	id := env.schedule(t, 50*time.Millisecond, models.Target{Channel: fallbackChannel, Recipient: "alice-hook"})
+	waitFor(t, "notification to be sent", func() bool {
+		return env.status(t, id).Status != models.Scheduled
+	})
+
+	n := env.status(t, id)
+	if n.Status != models.SentViaFallback {
+		t.Fatalf("status = %d, want SentViaFallback: the requested channel was not used", n.Status)
+	}
+	if n.TargetIndex != 1 {
+		t.Fatalf("target index = %d, want 1 in the persisted chain", n.TargetIndex)
+	}
+	if len(env.fallback.Sent()) != 1 || len(env.sent.Sent()) != 0 {
+		t.Fatalf("sends: fallback %d, primary %d; want only the preferred channel", len(env.fallback.Sent()), len(env.sent.Sent()))
+	}
+}
+
+func TestRequestedChannelAfterPreferredIsReportedAsSent(t *testing.T) {
+	env := newTestEnvWithProfiles(t, preferring{fallbackChannel})
+	env.fallback.Fail = func(models.Notification) error { return errors.New("hook is down") }
+
+	id := env.schedule(t, 50*time.Millisecond, models.Target{Channel: fallbackChannel, Recipient: "alice-hook"})
+	waitFor(t, "notification to be sent", func() bool {
+		return env.status(t, id).Status != models.Scheduled
+	})
+
+	n := env.status(t, id)
+	if n.Status != models.Sent {
+		t.Fatalf("status = %d, want Sent: delivered on the requested channel", n.Status)
+	}
+	if n.TargetIndex != 0 {
+		t.Fatalf("target index = %d, want 0 in the persisted chain", n.TargetIndex)
+	}
+	if sent := env.sent.Sent(); len(sent) != 1 || sent[0].Recipient != "alice" {
+		t.Fatalf("unexpected primary sends: %+v", sent)
+	}
+}
+
+func TestUpdatedNotificationIsSentOnce(t *testing.T) {
+	env := newTestEnv(t)
+	id := env.schedule(t, 150*time.Millisecond)
+
+	message := "updated"
+	sendAt := time.Now().Add(50 * time.Millisecond)
+	if _, err := env.svc.UpdateNotification(context.Background(), id, models.NotificationPatch{SendAt: &sendAt, Message: &message}); err != nil {
+		t.Fatalf("UpdateNotification: %v", err)
+	}
+	// Новая версия уходит в очередь через outbox, как и при создании
+	env.relay.flush(context.Background())
+
+	waitFor(t, "updated notification to be sent", func() bool {
+		return env.status(t, id).Status == models.Sent
+	})
+	// Копия первой версии срабатывает позже и должна быть отброшена
+	time.Sleep(250 * time.Millisecond)
+
+	sent := env.sent.Sent()
+	if len(sent) != 1 || sent[0].Message != "updated" {
+		t.Fatalf("unexpected sends: %+v", sent)
+	}
+}
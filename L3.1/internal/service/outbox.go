@@ -0,0 +1,198 @@
+package service
+
+import (
+	"context"
+	"log"
+	"math"
+	"sync/atomic"
+	"time"
+
+	"delayed-notifier/internal/cache"
+	"delayed-notifier/internal/metrics"
+	"delayed-notifier/internal/models"
+	"delayed-notifier/internal/queue"
+	"delayed-notifier/internal/repository"
+)
+
+// OutboxRelayConfig — параметры публикации сообщений из outbox
+type OutboxRelayConfig struct {
+	Interval   time.Duration
+	BatchSize  int
+	MaxBackoff time.Duration
+
+	// SweepInterval — как часто искать потерянные сообщения и чистить outbox
+	SweepInterval time.Duration
+	// LostAfter — через сколько после ожидаемого времени обработки запланированное
+	// уведомление считается потерянным брокером; 0 — не проверять
+	LostAfter time.Duration
+	// Retention — сколько хранить опубликованные сообщения
+	Retention time.Duration
+}
+
+// OutboxRelay публикует в очередь сообщения, записанные в outbox вместе с уведомлениями.
+// Сообщение, которое не удалось опубликовать, повторяется с экспоненциальной задержкой.
+type OutboxRelay struct {
+	outbox repository.OutboxRepo
+	queue  queue.Scheduler
+	cache  cache.NotifCache
+	cfg    OutboxRelayConfig
+
+	published atomic.Int64
+	failed    atomic.Int64
+}
+
+// OutboxMetrics — размер outbox и счётчики публикаций с момента запуска
+type OutboxMetrics struct {
+	models.OutboxStats
+	Published int64 `json:"published"`
+	Failed    int64 `json:"failed"`
+}
+
+func NewOutboxRelay(outbox repository.OutboxRepo, queue queue.Scheduler, cache cache.NotifCache, cfg OutboxRelayConfig) *OutboxRelay {
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Second
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = 5 * time.Minute
+	}
+	if cfg.SweepInterval <= 0 {
+		cfg.SweepInterval = time.Minute
+	}
+	if cfg.Retention <= 0 {
+		cfg.Retention = 24 * time.Hour
+	}
+
+	return &OutboxRelay{
+		outbox: outbox,
+		queue:  queue,
+		cache:  cache,
+		cfg:    cfg,
+	}
+}
+
+// Run разбирает outbox, пока не отменён ctx. Потерянные сообщения ищутся сразу
+// при запуске — брокер мог потерять их, пока сервис был остановлен, — и затем периодически.
+func (r *OutboxRelay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.cfg.Interval)
+	defer ticker.Stop()
+	sweep := time.NewTicker(r.cfg.SweepInterval)
+	defer sweep.Stop()
+
+	r.sweep(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.flush(ctx)
+		case <-sweep.C:
+			r.sweep(ctx)
+		}
+	}
+}
+
+// sweep перепубликует потерянные сообщения и удаляет старые опубликованные
+func (r *OutboxRelay) sweep(ctx context.Context) {
+	if r.cfg.LostAfter > 0 {
+		if err := r.RequeueLost(ctx); err != nil {
+			log.Printf("outbox: failed to requeue lost notifications: %v", err)
+		}
+	}
+
+	before := time.Now().Add(-r.cfg.Retention)
+	var deleted int64
+	for ctx.Err() == nil {
+		n, err := r.outbox.DeletePublished(ctx, before, r.cfg.BatchSize)
+		if err != nil {
+			log.Printf("outbox: failed to delete published messages: %v", err)
+			break
+		}
+		deleted += n
+		if n < int64(r.cfg.BatchSize) {
+			break
+		}
+	}
+	if deleted > 0 {
+		log.Printf("outbox: deleted %d published messages older than %s", deleted, r.cfg.Retention)
+	}
+}
+
+// flush публикует пачки, пока outbox не опустеет или очередь не начнёт отказывать
+func (r *OutboxRelay) flush(ctx context.Context) {
+	for ctx.Err() == nil {
+		published, failed, err := r.outbox.Relay(ctx, r.cfg.BatchSize, r.publish, r.retryDelay)
+		if err != nil {
+			log.Printf("outbox: relay failed: %v", err)
+			return
+		}
+		r.published.Add(int64(published))
+		r.failed.Add(int64(failed))
+		metrics.OutboxRelayed.WithLabelValues("ok").Add(float64(published))
+		metrics.OutboxRelayed.WithLabelValues("error").Add(float64(failed))
+
+		if failed > 0 || published < r.cfg.BatchSize {
+			return
+		}
+	}
+}
+
+func (r *OutboxRelay) publish(m *models.OutboxMessage) error {
+	return r.queue.Publish([]byte(m.Payload), m.PublishAt)
+}
+
+// retryDelay — 1, 2, 4... секунды, но не больше MaxBackoff
+func (r *OutboxRelay) retryDelay(attempts int) time.Duration {
+	delay := time.Duration(math.Pow(2, float64(min(attempts-1, 30)))) * time.Second
+	if delay > r.cfg.MaxBackoff {
+		return r.cfg.MaxBackoff
+	}
+	return delay
+}
+
+// RequeueLost заново публикует запланированные уведомления, которые не обработаны
+// спустя LostAfter после ожидаемого времени: их сообщение, по всей видимости, потерял брокер.
+// Новые версии вытесняют копии, которые в брокере всё-таки сохранились.
+func (r *OutboxRelay) RequeueLost(ctx context.Context) error {
+	lostBefore := time.Now().Add(-r.cfg.LostAfter)
+
+	total := 0
+	for ctx.Err() == nil {
+		requeued, err := r.outbox.RequeueLost(ctx, lostBefore, r.cfg.BatchSize)
+		if err != nil {
+			return err
+		}
+
+		for _, n := range requeued {
+			if err := r.cache.Set(ctx, n); err != nil {
+				log.Printf("warning: failed to set notification %s in cache: %v", n.ID, err)
+			}
+		}
+		total += len(requeued)
+		if len(requeued) < r.cfg.BatchSize {
+			break
+		}
+	}
+
+	if total > 0 {
+		metrics.OutboxRequeued.Add(float64(total))
+		log.Printf("outbox: requeued %d notifications lost by the broker", total)
+	}
+	return ctx.Err()
+}
+
+// Metrics возвращает размер outbox и счётчики публикаций
+func (r *OutboxRelay) Metrics(ctx context.Context) (*OutboxMetrics, error) {
+	stats, err := r.outbox.Stats(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &OutboxMetrics{
+		OutboxStats: *stats,
+		Published:   r.published.Load(),
+		Failed:      r.failed.Load(),
+	}, nil
+}
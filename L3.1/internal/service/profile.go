@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"delayed-notifier/internal/models"
+	"delayed-notifier/internal/tenant"
+)
+
+// localLayouts — допустимые форматы LocalSendAt
+var localLayouts = []string{
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+}
+
+// GetProfile возвращает профиль пользователя арендатора из контекста запроса
+func (s *NotificationService) GetProfile(ctx context.Context, userID string) (*models.UserProfile, error) {
+	tenantID, _ := tenant.FromContext(ctx)
+	return s.profiles.Get(ctx, tenantID, userID)
+}
+
+func (s *NotificationService) SaveProfile(ctx context.Context, p *models.UserProfile) error {
+	// Как и у уведомлений, арендатор определяется ключом запроса, а не телом
+	p.TenantID, _ = tenant.FromContext(ctx)
+
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	return s.profiles.Upsert(ctx, p)
+}
+
+// resolveSendAt переводит локальное время LocalSendAt в абсолютное SendAt.
+// Пояс берётся из уведомления, а если он не задан — из профиля пользователя.
+func (s *NotificationService) resolveSendAt(ctx context.Context, n *models.Notification) error {
+	if n.TimeZone != "" {
+		if _, err := time.LoadLocation(n.TimeZone); err != nil {
+			return fmt.Errorf("invalid time_zone %q", n.TimeZone)
+		}
+	}
+
+	if n.LocalSendAt == "" {
+		return nil
+	}
+
+	if n.TimeZone == "" {
+		p, err := s.profiles.Get(ctx, n.TenantID, n.UserID)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		if p != nil {
+			n.TimeZone = p.TimeZone
+		}
+	}
+
+	loc := time.UTC
+	if n.TimeZone != "" {
+		var err error
+		if loc, err = time.LoadLocation(n.TimeZone); err != nil {
+			return fmt.Errorf("invalid time_zone %q", n.TimeZone)
+		}
+	}
+
+	for _, layout := range localLayouts {
+		if t, err := time.ParseInLocation(layout, n.LocalSendAt, loc); err == nil {
+			n.SendAt = t
+			n.LocalSendAt = ""
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid local_send_at %q: expected YYYY-MM-DDTHH:MM[:SS]", n.LocalSendAt)
+}
+
+// recipientProfile возвращает профиль получателя уведомления в его арендаторе;
+// nil, если профиля нет или его не удалось загрузить
+func (s *NotificationService) recipientProfile(ctx context.Context, n *models.Notification) *models.UserProfile {
+	p, err := s.profiles.Get(ctx, n.TenantID, n.UserID)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			log.Printf("warning: failed to load profile of user %s: %v", n.UserID, err)
+		}
+		return nil
+	}
+	return p
+}
+
+// quietUntil возвращает конец тихих часов получателя, если t в них попадает
+func (s *NotificationService) quietUntil(ctx context.Context, n *models.Notification, t time.Time) (time.Time, bool) {
+	if p := s.recipientProfile(ctx, n); p != nil {
+		return p.QuietUntil(t)
+	}
+	return time.Time{}, false
+}
+
+// preferredChannels возвращает каналы, которые получатель предпочитает получать первыми
+func (s *NotificationService) preferredChannels(ctx context.Context, n *models.Notification) []models.ChannelType {
+	if p := s.recipientProfile(ctx, n); p != nil {
+		return p.PreferredChannels
+	}
+	return nil
+}
+
+// firstTarget возвращает канал цепочки, с которого начинается отправка уведомления
+func (s *NotificationService) firstTarget(ctx context.Context, n *models.Notification) int {
+	return n.TargetOrder(s.preferredChannels(ctx, n))[0]
+}
+
+// digestWindow возвращает окно дайджеста получателя; 0 — дайджест выключен
+func (s *NotificationService) digestWindow(ctx context.Context, n *models.Notification) time.Duration {
+	if p := s.recipientProfile(ctx, n); p != nil {
+		return p.Digest()
+	}
+	return 0
+}
+
+// inZone переводит t в пояс zone; при пустом или неизвестном поясе возвращает t как есть
+func inZone(t time.Time, zone string) time.Time {
+	if zone == "" {
+		return t
+	}
+	loc, err := time.LoadLocation(zone)
+	if err != nil {
+		return t
+	}
+	return t.In(loc)
+}
@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -10,36 +11,177 @@ import (
 	"time"
 
 	"delayed-notifier/internal/cache"
+	"delayed-notifier/internal/events"
+	"delayed-notifier/internal/metrics"
 	"delayed-notifier/internal/models"
 	"delayed-notifier/internal/queue"
+	"delayed-notifier/internal/recurrence"
 	"delayed-notifier/internal/repository"
 	"delayed-notifier/internal/sender"
+	"delayed-notifier/internal/tenant"
 
 	"github.com/wb-go/wbf/retry"
 )
 
+// EventPublisher рассылает события об изменении статуса уведомлений
+type EventPublisher interface {
+	Publish(ctx context.Context, e events.Event) error
+}
+
+// ErrNotScheduled возвращается при попытке изменить уже отправленное или отменённое уведомление
+var ErrNotScheduled = errors.New("notification is not scheduled")
+
+// ErrNotFailed возвращается при попытке повторить уведомление, которое не завершилось ошибкой
+var ErrNotFailed = errors.New("notification is not failed")
+
 type NotificationService struct {
-	repo   repository.NotificationRepo
-	cache  cache.NotifCache
-	queue  *queue.Queue
-	sender sender.Sender
+	repo        repository.NotificationRepo
+	deadLetters repository.DeadLetterRepo
+	profiles    repository.UserProfileRepo
+	cache       cache.NotifCache
+	queue       queue.Scheduler
+	sender      sender.Sender
+	retry       retry.Strategy
+	events      EventPublisher
 }
 
-func NewNotificationService(repo repository.NotificationRepo, cache cache.NotifCache, queue *queue.Queue, sender sender.Sender) *NotificationService {
+// DefaultRetryStrategy — 5 попыток с задержкой 1, 2, 4, 8 минут
+var DefaultRetryStrategy = retry.Strategy{
+	Attempts: 5,
+	Delay:    1 * time.Minute,
+	Backoff:  2,
+}
+
+func NewNotificationService(repo repository.NotificationRepo, deadLetters repository.DeadLetterRepo, profiles repository.UserProfileRepo, cache cache.NotifCache, queue queue.Scheduler, sender sender.Sender, retryStrategy retry.Strategy, events EventPublisher) *NotificationService {
+	if retryStrategy.Attempts <= 0 {
+		retryStrategy = DefaultRetryStrategy
+	}
+	if retryStrategy.Backoff < 1 {
+		retryStrategy.Backoff = 1
+	}
+
 	return &NotificationService{
-		repo:   repo,
-		cache:  cache,
-		queue:  queue,
-		sender: sender,
+		repo:        repo,
+		deadLetters: deadLetters,
+		profiles:    profiles,
+		cache:       cache,
+		queue:       queue,
+		sender:      sender,
+		retry:       retryStrategy,
+		events:      events,
+	}
+}
+
+// CreateNotification создаёт уведомление и ставит его в очередь. Если уведомление
+// с тем же ключом идемпотентности уже есть, n заполняется им и возвращается false.
+func (s *NotificationService) CreateNotification(ctx context.Context, n *models.Notification) (bool, error) {
+	if found, err := s.findByIdempotencyKey(ctx, n); found || err != nil {
+		return false, err
+	}
+
+	if err := s.prepare(ctx, n); err != nil {
+		return false, err
+	}
+
+	err := s.enqueue(ctx, n)
+	if errors.Is(err, sql.ErrNoRows) && n.IdempotencyKey != "" {
+		// Параллельный запрос с тем же ключом успел раньше
+		found, err := s.findByIdempotencyKey(ctx, n)
+		if err == nil && !found {
+			err = fmt.Errorf("notification with idempotency key %q not found", n.IdempotencyKey)
+		}
+		return false, err
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// findByIdempotencyKey заменяет n ранее созданным уведомлением с тем же ключом, если оно есть
+func (s *NotificationService) findByIdempotencyKey(ctx context.Context, n *models.Notification) (bool, error) {
+	if n.IdempotencyKey == "" {
+		return false, nil
+	}
+
+	existing, err := s.repo.GetByIdempotencyKey(ctx, n.IdempotencyKey)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
 	}
+
+	*n = *existing
+	return true, nil
 }
 
-func (s *NotificationService) CreateNotification(ctx context.Context, n *models.Notification) error {
+// prepare проверяет новое уведомление и заполняет вычисляемые поля
+func (s *NotificationService) prepare(ctx context.Context, n *models.Notification) error {
+	// Арендатор определяется ключом запроса, а не телом
+	n.TenantID, _ = tenant.FromContext(ctx)
+
+	if err := s.resolveSendAt(ctx, n); err != nil {
+		return err
+	}
+
 	if n.SendAt.Before(time.Now()) {
 		return errors.New("send time must be in the future")
 	}
 
-	// Создаём в БД
+	if n.IsRecurring() {
+		if _, err := recurrence.Parse(n.Schedule, inZone(n.SendAt, n.TimeZone)); err != nil {
+			return err
+		}
+		if n.MaxOccurrences < 0 {
+			return errors.New("max_occurrences must not be negative")
+		}
+		if n.RepeatUntil != nil && n.RepeatUntil.Before(n.SendAt) {
+			return errors.New("repeat_until must be after send time")
+		}
+	}
+
+	if n.Message == "" && n.Template == "" {
+		return errors.New("message or template is required")
+	}
+
+	if n.Priority == 0 {
+		n.Priority = models.PriorityNormal
+	}
+	if !n.Priority.Valid() {
+		return fmt.Errorf("priority must be between %d and %d", models.PriorityLow, models.MaxPriority)
+	}
+
+	// Каналы и шаблон проверяем сразу, а не в момент отправки
+	for i := range n.Targets() {
+		target := n.ForTarget(i)
+		if i > 0 && target.Recipient == "" {
+			return fmt.Errorf("fallback %d: recipient is required", i)
+		}
+		if v, ok := s.sender.(sender.Validator); ok {
+			if err := v.Validate(target); err != nil {
+				if i > 0 {
+					return fmt.Errorf("fallback %d: %w", i, err)
+				}
+				return err
+			}
+		}
+	}
+
+	// Первое срабатывание само открывает серию
+	n.SeriesID = ""
+	n.Occurrence = 1
+	n.TargetIndex = s.firstTarget(ctx, n)
+	n.DeliveredChannel = nil
+
+	return nil
+}
+
+// enqueue сохраняет уведомление в БД и кэше. В очередь его публикует OutboxRelay:
+// сообщение пишется в outbox в той же транзакции, что и уведомление.
+func (s *NotificationService) enqueue(ctx context.Context, n *models.Notification) error {
+	// Создаём в БД вместе с сообщением для очереди
 	if err := s.repo.Create(ctx, n); err != nil {
 		return err
 	}
@@ -49,24 +191,31 @@ func (s *NotificationService) CreateNotification(ctx context.Context, n *models.
 		log.Printf("warning: failed to set notification %s in cache: %v", n.ID, err)
 	}
 
-	// Публикуем в очередь
-	body, err := json.Marshal(n)
-	if err != nil {
-		return errors.New("failed to serialize notification")
-	}
+	s.emit(ctx, events.NewEvent(events.Created, n))
+	return nil
+}
 
-	if err := s.queue.Publish(body, n.SendAt); err != nil {
-		log.Printf("failed to publish message: %v", err)
-		return errors.New("failed to enqueue notification")
-	}
+// emit учитывает событие в метриках и рассылает его;
+// ошибка рассылки не влияет на обработку уведомления
+func (s *NotificationService) emit(ctx context.Context, e events.Event) {
+	metrics.Notifications.WithLabelValues(e.Type, e.Channel.String()).Inc()
 
-	return nil
+	if s.events == nil {
+		return
+	}
+	if err := s.events.Publish(ctx, e); err != nil {
+		log.Printf("warning: failed to publish %s event for notification %s: %v", e.Type, e.NotificationID, err)
+	}
 }
 
 func (s *NotificationService) GetNotification(ctx context.Context, id int) (*models.Notification, error) {
 	if notif, err := s.cache.Get(ctx, id); err != nil {
 		return nil, err
 	} else if notif != nil {
+		// Кэш общий для всех арендаторов — чужое уведомление выглядит как отсутствующее
+		if !tenant.Allows(ctx, notif.TenantID) {
+			return nil, sql.ErrNoRows
+		}
 		return notif, nil
 	}
 
@@ -83,43 +232,190 @@ func (s *NotificationService) GetNotification(ctx context.Context, id int) (*mod
 	return notif, nil
 }
 
-func (s *NotificationService) ListNotifications(ctx context.Context) ([]*models.Notification, error) {
-	notif, err := s.repo.GetAll(ctx)
+const (
+	defaultPageSize = 50
+	maxPageSize     = 500
+)
+
+// ListNotifications возвращает страницу уведомлений по фильтру
+func (s *NotificationService) ListNotifications(ctx context.Context, filter models.NotificationFilter) (*models.NotificationPage, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = defaultPageSize
+	}
+	if filter.Limit > maxPageSize {
+		filter.Limit = maxPageSize
+	}
+	limit := filter.Limit
+
+	// Берём на одну запись больше, чтобы понять, есть ли следующая страница
+	filter.Limit++
+	notif, err := s.repo.GetAll(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &models.NotificationPage{Items: notif}
+	if len(notif) > limit {
+		page.Items = notif[:limit]
+		last := page.Items[limit-1]
+		id, err := strconv.Atoi(last.ID)
+		if err != nil {
+			return nil, err
+		}
+		page.NextCursor = models.Cursor{CreatedAt: last.CreatedAt, ID: id}.Encode()
+	}
+	if page.Items == nil {
+		page.Items = []*models.Notification{}
+	}
+
+	return page, nil
+}
+
+// UpdateNotification меняет время, текст или получателя запланированного уведомления.
+// Новая версия публикуется через outbox, как и при создании; ранее опубликованные
+// копии будут отброшены при обработке.
+func (s *NotificationService) UpdateNotification(ctx context.Context, id int, patch models.NotificationPatch) (*models.Notification, error) {
+	if patch.SendAt == nil && patch.Message == nil && patch.Recipient == nil {
+		return nil, errors.New("nothing to update")
+	}
+	if patch.SendAt != nil && patch.SendAt.Before(time.Now()) {
+		return nil, errors.New("send time must be in the future")
+	}
+
+	current, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if current.Status != models.Scheduled {
+		return nil, ErrNotScheduled
+	}
+
+	// Проверяем изменённое уведомление так же, как при создании
+	candidate := *current
+	if patch.Message != nil {
+		candidate.Message = *patch.Message
+	}
+	if patch.Recipient != nil {
+		candidate.Recipient = *patch.Recipient
+	}
+	if candidate.Message == "" && candidate.Template == "" {
+		return nil, errors.New("message or template is required")
+	}
+	if v, ok := s.sender.(sender.Validator); ok {
+		if err := v.Validate(candidate); err != nil {
+			return nil, err
+		}
+	}
+
+	n, err := s.repo.Update(ctx, id, patch)
+	if errors.Is(err, sql.ErrNoRows) {
+		// Успело отправиться или отмениться между проверкой и обновлением
+		return nil, ErrNotScheduled
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	return notif, nil
+	if err := s.cache.Set(ctx, n); err != nil {
+		log.Printf("warning: failed to update notification %s in cache: %v", n.ID, err)
+	}
+	s.emit(ctx, events.NewEvent(events.Updated, n))
+
+	return n, nil
+}
+
+// ListSeries возвращает все срабатывания серии, к которой относится уведомление
+func (s *NotificationService) ListSeries(ctx context.Context, id int) ([]*models.Notification, error) {
+	seriesID, err := s.seriesOf(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetSeries(ctx, seriesID)
 }
 
+// CancelNotification отменяет уведомление вместе со всеми будущими срабатываниями его серии
 func (s *NotificationService) CancelNotification(ctx context.Context, id int) error {
-	// Удаляем из БД
-	if err := s.repo.Delete(ctx, id); err != nil {
+	notif, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	seriesID, err := strconv.Atoi(seriesKey(notif))
+	if err != nil {
+		return err
+	}
+
+	// Отменяем в БД: строки остаются со статусом Canceled
+	canceled, err := s.repo.CancelSeries(ctx, seriesID)
+	if err != nil {
 		return err
 	}
+	if len(canceled) == 0 {
+		return ErrNotScheduled
+	}
 
 	// Удаляем из кэша
-	if err := s.cache.Delete(ctx, id); err != nil {
-		log.Printf("warning: failed to delete notification %d from cache: %v", id, err)
+	for _, cid := range canceled {
+		if err := s.cache.Delete(ctx, cid); err != nil {
+			log.Printf("warning: failed to delete notification %d from cache: %v", cid, err)
+		}
+
+		e := events.NewEvent(events.Canceled, notif)
+		e.NotificationID = strconv.Itoa(cid)
+		e.Status = models.Canceled
+		s.emit(ctx, e)
 	}
 
 	return nil
 }
 
-func (s *NotificationService) RestoreCacheFromDB(ctx context.Context) error {
-	active, err := s.repo.GetActive(ctx)
+func (s *NotificationService) seriesOf(ctx context.Context, id int) (int, error) {
+	notif, err := s.repo.GetByID(ctx, id)
 	if err != nil {
-		return err
+		return 0, err
+	}
+
+	return strconv.Atoi(seriesKey(notif))
+}
+
+func seriesKey(n *models.Notification) string {
+	if n.SeriesID != "" {
+		return n.SeriesID
+	}
+	return n.ID
+}
+
+// currentState возвращает состояние уведомления из Postgres — источника истины —
+// и чинит запись кэша, если она разошлась с базой. Если база недоступна,
+// решение принимается по кэшу; без записи в кэше возвращается ошибка.
+func (s *NotificationService) currentState(ctx context.Context, id int) (*models.Notification, error) {
+	cached, cacheErr := s.cache.Get(ctx, id)
+	if cacheErr != nil {
+		log.Printf("warning: failed to check cache for notif %d: %v", id, cacheErr)
 	}
 
-	for _, notif := range active {
-		if err := s.cache.Set(ctx, notif); err != nil {
-			log.Printf("не удалось добавить в кэш уведомление %s: %v", notif.ID, err)
+	actual, err := s.repo.GetByID(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		if cached != nil {
+			metrics.CacheMismatches.WithLabelValues("orphan", "read").Inc()
+			if err := s.cache.Delete(ctx, id); err != nil {
+				log.Printf("warning: failed to delete notification %d from cache: %v", id, err)
+			}
+		}
+		return nil, err
+	}
+	if err != nil {
+		if cached != nil {
+			log.Printf("warning: failed to load notification %d, using cached state: %v", id, err)
+			return cached, nil
 		}
+		return nil, err
 	}
 
-	log.Printf("кэш восстановлен: добавлено %d уведомлений", len(active))
-	return nil
+	if cacheErr == nil {
+		repairCache(ctx, s.cache, cached, actual, "read")
+	}
+	return actual, nil
 }
 
 func (s *NotificationService) UpdateNotificationStatus(ctx context.Context, id int, status models.StatusType) error {
@@ -151,17 +447,33 @@ func (s *NotificationService) UpdateNotificationStatus(ctx context.Context, id i
 		log.Printf("warning: failed to update notification %d in cache: %v", id, err)
 	}
 
+	s.emit(ctx, events.NewEvent(statusEvent(status), notif))
 	return nil
 }
 
-func (s *NotificationService) IncrementRetryCount(ctx context.Context, id int) error {
+// statusEvent возвращает тип события для перехода в статус
+func statusEvent(status models.StatusType) string {
+	switch status {
+	case models.Sent, models.SentViaFallback:
+		return events.Sent
+	case models.Failed:
+		return events.Failed
+	case models.Canceled:
+		return events.Canceled
+	default:
+		return events.Updated
+	}
+}
+
+// IncrementRetryCount увеличивает счётчик попыток и возвращает новое значение
+func (s *NotificationService) IncrementRetryCount(ctx context.Context, id int) (int, error) {
 	// Получаем уведомление из БД
 	notif, err := s.repo.GetByID(ctx, id)
 	if err != nil {
-		return err
+		return 0, err
 	}
 	if notif == nil {
-		return fmt.Errorf("notification %d not found", id)
+		return 0, fmt.Errorf("notification %d not found", id)
 	}
 
 	// Увеличиваем счетчик ретраев
@@ -170,7 +482,7 @@ func (s *NotificationService) IncrementRetryCount(ctx context.Context, id int) e
 
 	// Обновляем в базе
 	if err := s.repo.UpdateRetryCount(ctx, id, notif.Retry); err != nil {
-		return err
+		return 0, err
 	}
 
 	// Обновляем в кэше
@@ -178,47 +490,318 @@ func (s *NotificationService) IncrementRetryCount(ctx context.Context, id int) e
 		log.Printf("warning: failed to update retry count for notification %d in cache: %v", id, err)
 	}
 
+	return notif.Retry, nil
+}
+
+// markDelivered помечает уведомление отправленным и запоминает канал доставки.
+// Доставка не тем каналом, который запросил создатель уведомления, получает
+// статус SentViaFallback — даже если этот канал получатель предпочитает.
+func (s *NotificationService) markDelivered(ctx context.Context, id int, notif *models.Notification, target models.Notification) error {
+	status := models.Sent
+	if notif.IsFallback(target) {
+		status = models.SentViaFallback
+	}
+
+	if err := s.repo.MarkDelivered(ctx, id, status, target.Channel); err != nil {
+		return err
+	}
+
+	notif, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.cache.Set(ctx, notif); err != nil {
+		log.Printf("warning: failed to update notification %d in cache: %v", id, err)
+	}
+
+	s.emit(ctx, events.NewEvent(events.Sent, notif))
+	return nil
+}
+
+// switchTarget переводит уведомление на канал цепочки с номером index
+func (s *NotificationService) switchTarget(ctx context.Context, id int, index int) error {
+	if err := s.repo.SwitchTarget(ctx, id, index); err != nil {
+		return err
+	}
+
+	notif, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.cache.Set(ctx, notif); err != nil {
+		log.Printf("warning: failed to update notification %d in cache: %v", id, err)
+	}
+
 	return nil
 }
 
+// ProcessNotification делает одну попытку отправки. При неудаче уведомление
+// публикуется повторно с экспоненциальной задержкой, пока не исчерпаны попытки.
+// Ошибка возвращается только если не удалось сохранить результат — тогда
+// сообщение не подтверждается и будет доставлено повторно.
 func (s *NotificationService) ProcessNotification(ctx context.Context, notif *models.Notification) error {
 	id, err := strconv.Atoi(notif.ID)
 	if err != nil {
 		return err
 	}
 
-	// Проверяем кэш
-	cachedNotif, err := s.cache.Get(ctx, id)
-	if err != nil {
-		log.Printf("failed to check cache for notif %d: %v", id, err)
-	} else if cachedNotif == nil {
-		log.Printf("notification %d not found in cache (probably canceled), skipping send", id)
+	// Проверяем актуальное состояние: отменено ли, не отправлено ли уже, не изменено ли
+	current, err := s.currentState(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		log.Printf("notification %d not found, skipping send", id)
 		return nil
-	} else if cachedNotif.Status == models.Canceled {
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load notification %d: %w", id, err)
+	}
+	if current.Status == models.Canceled {
 		log.Printf("notification %d is canceled, skipping send", id)
 		return nil
+	} else if current.Status != models.Scheduled {
+		// Повторная доставка уже обработанного сообщения. Если прошлая обработка упала
+		// после смены статуса, следующее срабатывание серии ещё не создано — создаём его
+		log.Printf("notification %d already processed, skipping send", id)
+		return s.scheduleNextOccurrence(ctx, current)
+	} else if notif.Version < current.Version {
+		// Уведомление изменили после публикации — актуальная копия уже в очереди
+		log.Printf("notification %d: stale version %d (current %d), skipping send", id, notif.Version, current.Version)
+		return nil
 	}
 
-	// Стратегия повторов
-	strategy := retry.Strategy{
-		Attempts: 5, // число попыток
-		Delay:    1 * time.Minute,
-		Backoff:  2, // экспоненциальная задержка
+	// Тихие часы получателя — откладываем до их окончания
+	if until, quiet := s.quietUntil(ctx, notif, time.Now()); quiet {
+		log.Printf("notification %d: quiet hours for user %s, postponed until %s", id, notif.UserID, until.Format(time.RFC3339))
+
+		return s.republish(ctx, id, notif, until)
 	}
 
-	// Пробуем отправить с retry
-	if err := retry.Do(func() error {
-		if sendErr := s.sender.Send(*notif); sendErr != nil {
-			if updateErr := s.IncrementRetryCount(ctx, id); updateErr != nil {
-				log.Printf("failed to increment retry count for notif %d: %v", id, updateErr)
-			}
-			return sendErr
+	// Дайджест: уведомления тому же получателю в пределах окна уходят одним сообщением
+	digestID, members, err := s.collectDigest(ctx, notif)
+	if errors.Is(err, errDigestBusy) {
+		log.Printf("notification %d: %v, rechecking in %s", id, err, digestRecheck)
+
+		return s.republish(ctx, id, notif, time.Now().Add(digestRecheck))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to collect digest for notif %d: %w", id, err)
+	}
+
+	target := notif.ForTarget(notif.TargetIndex)
+	if digestID != 0 {
+		digest, err := s.digestMessage(notif, members)
+		if err != nil {
+			log.Printf("notification %d: failed to build digest %d, sending alone: %v", id, digestID, err)
+			s.releaseDigest(ctx, digestID)
+			digestID = 0
+		} else {
+			target = digest
+		}
+	}
+
+	sendErr := s.send(ctx, &target)
+	notif.LastError = target.LastError
+	if digestID != 0 && sendErr != nil {
+		// Дайджест не ушёл — дальше уведомления обрабатываются по отдельности
+		s.releaseDigest(ctx, digestID)
+	}
+
+	// Упёрлись в лимит — откладываем без траты попытки
+	var throttled *sender.ThrottledError
+	if errors.As(sendErr, &throttled) {
+		log.Printf("notification %d: %v", id, throttled)
+
+		return s.republish(ctx, id, notif, time.Now().Add(throttled.RetryAfter))
+	}
+
+	if sendErr == nil && digestID != 0 {
+		return s.markDigestSent(ctx, digestID, &target)
+	}
+	if sendErr == nil {
+		metrics.SchedulingLag.WithLabelValues(target.Channel.String()).Observe(time.Since(notif.SendAt).Seconds())
+		if err := s.markDelivered(ctx, id, notif, target); err != nil {
+			return err
+		}
+		return s.scheduleNextOccurrence(ctx, notif)
+	}
+
+	retries, err := s.IncrementRetryCount(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to increment retry count for notif %d: %w", id, err)
+	}
+	notif.Retry = retries
+
+	if retries < s.retry.Attempts {
+		delay := s.retryDelay(retries)
+		metrics.Retries.WithLabelValues(target.Channel.String()).Inc()
+		log.Printf("notification %d: attempt %d failed: %v; retrying in %s", id, retries, sendErr, delay)
+
+		return s.republish(ctx, id, notif, time.Now().Add(delay))
+	}
+
+	// Попытки для текущего канала исчерпаны — переходим к следующему
+	// в порядке, который предпочитает получатель
+	if index, ok := notif.NextTarget(s.preferredChannels(ctx, notif)); ok {
+		next := notif.ForTarget(index)
+		log.Printf("notification %d: %s failed after %d attempts: %v; falling back to %s", id, target.Channel, retries, sendErr, next.Channel)
+
+		if err := s.switchTarget(ctx, id, index); err != nil {
+			return fmt.Errorf("failed to switch notification %d to fallback: %w", id, err)
 		}
+		notif.TargetIndex = index
+		notif.Retry = 0
+
+		return s.republish(ctx, id, notif, time.Now())
+	}
+
+	log.Printf("notification %d failed after %d attempts: %v", id, retries, sendErr)
+	if err := s.UpdateNotificationStatus(ctx, id, models.Failed); err != nil {
+		return err
+	}
+	s.addDeadLetter(ctx, notif, fmt.Sprintf("failed after %d attempts: %v", retries, sendErr))
+
+	return s.scheduleNextOccurrence(ctx, notif)
+}
+
+// republish снова публикует уведомление с отправкой в at и запоминает в БД, когда ждать
+// его обработки: по этому времени OutboxRelay находит сообщения, потерянные брокером
+func (s *NotificationService) republish(ctx context.Context, id int, notif *models.Notification, at time.Time) error {
+	if err := s.repo.MarkQueued(ctx, id, at); err != nil {
+		return fmt.Errorf("failed to mark notification %d queued: %w", id, err)
+	}
+
+	body, err := json.Marshal(notif)
+	if err != nil {
+		return errors.New("failed to serialize notification")
+	}
+	return s.queue.Publish(body, at)
+}
+
+// send отправляет уведомление и записывает попытку в журнал доставки
+func (s *NotificationService) send(ctx context.Context, notif *models.Notification) error {
+	started := time.Now()
+	sendErr := s.sender.Send(*notif)
+
+	var throttled *sender.ThrottledError
+	if errors.As(sendErr, &throttled) {
+		return sendErr
+	}
+
+	elapsed := time.Since(started)
+	attempt := &models.Attempt{
+		NotificationID: notif.ID,
+		Channel:        notif.Channel,
+		AttemptedAt:    started,
+		DurationMs:     elapsed.Milliseconds(),
+	}
+	result := "ok"
+	if sendErr != nil {
+		attempt.Error = sendErr.Error()
+		notif.LastError = attempt.Error
+		result = "error"
+	}
+	metrics.SendDuration.WithLabelValues(notif.Channel.String(), result).Observe(elapsed.Seconds())
+
+	if err := s.repo.AddAttempt(ctx, attempt); err != nil {
+		log.Printf("warning: failed to record attempt for notification %s: %v", notif.ID, err)
+	}
+
+	return sendErr
+}
+
+// ListAttempts возвращает журнал попыток отправки уведомления
+func (s *NotificationService) ListAttempts(ctx context.Context, id int) ([]*models.Attempt, error) {
+	if _, err := s.repo.GetByID(ctx, id); err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetAttempts(ctx, id)
+}
+
+// retryDelay возвращает задержку перед повтором после attempt неудачных попыток
+func (s *NotificationService) retryDelay(attempt int) time.Duration {
+	delay := s.retry.Delay
+	for i := 1; i < attempt; i++ {
+		delay = time.Duration(float64(delay) * s.retry.Backoff)
+	}
+	return delay
+}
+
+// scheduleNextOccurrence создаёт и публикует следующее срабатывание повторяющегося уведомления
+func (s *NotificationService) scheduleNextOccurrence(ctx context.Context, prev *models.Notification) error {
+	if !prev.IsRecurring() {
+		return nil
+	}
+	if prev.MaxOccurrences > 0 && prev.Occurrence >= prev.MaxOccurrences {
+		log.Printf("series %s finished: reached %d occurrences", seriesKey(prev), prev.MaxOccurrences)
+		return nil
+	}
+
+	seriesID, err := strconv.Atoi(seriesKey(prev))
+	if err != nil {
+		return err
+	}
+	series, err := s.repo.GetSeries(ctx, seriesID)
+	if err != nil || len(series) == 0 {
+		return fmt.Errorf("failed to load series %d: %w", seriesID, err)
+	}
+
+	// Следующее срабатывание уже создано — например, при повторной отправке из DLQ
+	if last := series[len(series)-1]; last.Occurrence > prev.Occurrence {
+		return nil
+	}
+	first := series[0]
+
+	// Правило считаем в поясе получателя, чтобы "каждый день в 9:00" не сдвигалось
+	rule, err := recurrence.Parse(prev.Schedule, inZone(first.SendAt, prev.TimeZone))
+	if err != nil {
+		return err
+	}
+
+	// Пропускаем срабатывания, время которых уже прошло (например, пока сервис был остановлен)
+	now := time.Now()
+	next := rule.Next(inZone(prev.SendAt, prev.TimeZone))
+	for !next.IsZero() && next.Before(now) {
+		next = rule.Next(next)
+	}
+
+	if next.IsZero() || (prev.RepeatUntil != nil && next.After(*prev.RepeatUntil)) {
+		log.Printf("series %d finished: no more occurrences", seriesID)
 		return nil
-	}, strategy); err != nil {
-		log.Printf("notification %d failed after retries: %v", id, err)
-		return s.UpdateNotificationStatus(ctx, id, models.Failed)
 	}
 
-	return s.UpdateNotificationStatus(ctx, id, models.Sent)
+	n := &models.Notification{
+		UserID:         prev.UserID,
+		TenantID:       prev.TenantID,
+		Channel:        prev.Channel,
+		Recipient:      prev.Recipient,
+		Message:        prev.Message,
+		Priority:       prev.Priority,
+		SendAt:         next,
+		Schedule:       prev.Schedule,
+		RepeatUntil:    prev.RepeatUntil,
+		MaxOccurrences: prev.MaxOccurrences,
+		Template:       prev.Template,
+		Locale:         prev.Locale,
+		Variables:      prev.Variables,
+		TimeZone:       prev.TimeZone,
+		Fallbacks:      prev.Fallbacks,
+		Subject:        prev.Subject,
+		HTML:           prev.HTML,
+		Email:          prev.Email,
+		SeriesID:       strconv.Itoa(seriesID),
+		Occurrence:     prev.Occurrence + 1,
+	}
+	n.TargetIndex = s.firstTarget(ctx, n)
+
+	err = s.enqueue(ctx, n)
+	if errors.Is(err, sql.ErrNoRows) {
+		// Срабатывание одновременно создал другой воркер, обрабатывающий повторную доставку
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to schedule next occurrence of series %d: %w", seriesID, err)
+	}
+
+	log.Printf("series %d: occurrence %d scheduled at %s", seriesID, n.Occurrence, n.SendAt.Format(time.RFC3339))
+	return nil
 }
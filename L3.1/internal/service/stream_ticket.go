@@ -0,0 +1,64 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"time"
+
+	"delayed-notifier/internal/tenant"
+	"delayed-notifier/internal/ticket"
+)
+
+// ErrInvalidTicket возвращается для неизвестного, уже использованного или просроченного билета
+var ErrInvalidTicket = errors.New("invalid or expired stream ticket")
+
+// streamTicketTTL — сколько билет ждёт подключения к потоку событий
+const streamTicketTTL = 30 * time.Second
+
+// StreamTicket — одноразовый билет на подключение к потоку событий.
+// EventSource не умеет задавать заголовки, поэтому браузер передаёт билет
+// в адресе запроса: в журналы попадает билет, а не API-ключ.
+type StreamTicket struct {
+	Ticket    string    `json:"ticket"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// IssueStreamTicket выдаёт билет на поток событий с правами запроса ctx
+func (s *APIKeyService) IssueStreamTicket(ctx context.Context) (*StreamTicket, error) {
+	secret := make([]byte, 24)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+
+	t := &StreamTicket{
+		Ticket:    base64.RawURLEncoding.EncodeToString(secret),
+		ExpiresAt: time.Now().Add(streamTicketTTL),
+	}
+	tenantID, scoped := tenant.FromContext(ctx)
+
+	ok, err := s.tickets.Put(ctx, t.Ticket, ticket.Grant{TenantID: tenantID, Scoped: scoped}, streamTicketTTL)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errors.New("stream ticket collision, try again")
+	}
+	return t, nil
+}
+
+// RedeemStreamTicket погашает билет и возвращает контекст с правами, с которыми он выдан
+func (s *APIKeyService) RedeemStreamTicket(ctx context.Context, t string) (context.Context, error) {
+	g, ok, err := s.tickets.Take(ctx, t)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrInvalidTicket
+	}
+	if g.Scoped {
+		ctx = tenant.WithID(ctx, g.TenantID)
+	}
+	return ctx, nil
+}
@@ -0,0 +1,48 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"delayed-notifier/internal/tenant"
+	"delayed-notifier/internal/ticket"
+)
+
+func TestStreamTicketIsSingleUse(t *testing.T) {
+	s := NewAPIKeyService(nil, ticket.NewMemoryStore())
+
+	issued, err := s.IssueStreamTicket(tenant.WithID(context.Background(), "acme"))
+	if err != nil {
+		t.Fatalf("IssueStreamTicket: %v", err)
+	}
+
+	ctx, err := s.RedeemStreamTicket(context.Background(), issued.Ticket)
+	if err != nil {
+		t.Fatalf("RedeemStreamTicket: %v", err)
+	}
+	if id, ok := tenant.FromContext(ctx); !ok || id != "acme" {
+		t.Fatalf("redeemed tenant = %q, %v; want acme", id, ok)
+	}
+
+	if _, err := s.RedeemStreamTicket(context.Background(), issued.Ticket); !errors.Is(err, ErrInvalidTicket) {
+		t.Fatalf("second redeem error = %v, want ErrInvalidTicket", err)
+	}
+}
+
+func TestStreamTicketKeepsAdminScope(t *testing.T) {
+	s := NewAPIKeyService(nil, ticket.NewMemoryStore())
+
+	// Административный запрос идёт без арендатора и видит события всех арендаторов
+	issued, err := s.IssueStreamTicket(context.Background())
+	if err != nil {
+		t.Fatalf("IssueStreamTicket: %v", err)
+	}
+	ctx, err := s.RedeemStreamTicket(context.Background(), issued.Ticket)
+	if err != nil {
+		t.Fatalf("RedeemStreamTicket: %v", err)
+	}
+	if _, ok := tenant.FromContext(ctx); ok {
+		t.Fatal("admin ticket must not restrict the stream to a tenant")
+	}
+}
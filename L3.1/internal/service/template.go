@@ -0,0 +1,157 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"strconv"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"delayed-notifier/internal/models"
+	"delayed-notifier/internal/repository"
+)
+
+type TemplateService struct {
+	repo repository.TemplateRepo
+}
+
+func NewTemplateService(repo repository.TemplateRepo) *TemplateService {
+	return &TemplateService{
+		repo: repo,
+	}
+}
+
+func (s *TemplateService) CreateTemplate(ctx context.Context, t *models.Template) error {
+	if err := validateTemplate(t); err != nil {
+		return err
+	}
+	return s.repo.Create(ctx, t)
+}
+
+func (s *TemplateService) GetTemplate(ctx context.Context, id int) (*models.Template, error) {
+	return s.repo.GetByID(ctx, id)
+}
+
+func (s *TemplateService) ListTemplates(ctx context.Context) ([]*models.Template, error) {
+	return s.repo.GetAll(ctx)
+}
+
+func (s *TemplateService) UpdateTemplate(ctx context.Context, id int, t *models.Template) error {
+	if err := validateTemplate(t); err != nil {
+		return err
+	}
+	t.ID = strconv.Itoa(id)
+	return s.repo.Update(ctx, t)
+}
+
+func (s *TemplateService) DeleteTemplate(ctx context.Context, id int) error {
+	return s.repo.Delete(ctx, id)
+}
+
+// Render подставляет переменные уведомления в шаблон его канала и языка.
+// Уведомления без шаблона возвращаются без изменений.
+func (s *TemplateService) Render(n models.Notification) (models.Notification, error) {
+	if n.Template == "" {
+		return n, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	t, err := s.repo.Find(ctx, n.Template, n.Channel, localeChain(n.Locale))
+	if err != nil {
+		return n, fmt.Errorf("template %q for channel %s and locale %q not found: %w", n.Template, n.Channel, n.Locale, err)
+	}
+
+	subject, err := execute(t, "subject", t.Subject, n.Variables)
+	if err != nil {
+		return n, err
+	}
+	body, err := execute(t, "body", t.Body, n.Variables)
+	if err != nil {
+		return n, err
+	}
+
+	// Тема, заданная в самом уведомлении, важнее темы шаблона
+	if n.Subject == "" {
+		n.Subject = subject
+	}
+	n.Message = body
+	n.HTML = t.HTML
+	return n, nil
+}
+
+// localeChain возвращает языки в порядке предпочтения: "pt-BR" -> "pt-BR", "pt", ""
+func localeChain(locale string) []string {
+	chain := []string{}
+	if locale != "" {
+		chain = append(chain, locale)
+		if i := strings.IndexAny(locale, "-_"); i > 0 {
+			chain = append(chain, locale[:i])
+		}
+	}
+	return append(chain, "")
+}
+
+func validateTemplate(t *models.Template) error {
+	if strings.TrimSpace(t.Name) == "" {
+		return errors.New("template name is required")
+	}
+	if _, err := parse(t, "subject", t.Subject); err != nil {
+		return err
+	}
+	if _, err := parse(t, "body", t.Body); err != nil {
+		return err
+	}
+	return nil
+}
+
+// executor — общий интерфейс text/template и html/template
+type executor interface {
+	Execute(wr io.Writer, data any) error
+}
+
+func parse(t *models.Template, part, text string) (executor, error) {
+	if t.HTML {
+		tpl, err := htmltemplate.New(part).Option("missingkey=error").Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid template %s: %w", part, err)
+		}
+		return tpl, nil
+	}
+
+	tpl, err := texttemplate.New(part).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template %s: %w", part, err)
+	}
+	return tpl, nil
+}
+
+func execute(t *models.Template, part, text string, vars models.Variables) (string, error) {
+	// Тема письма всегда текстовая
+	if part == "subject" {
+		plain := *t
+		plain.HTML = false
+		t = &plain
+	}
+
+	tpl, err := parse(t, part, text)
+	if err != nil {
+		return "", err
+	}
+
+	if vars == nil {
+		vars = models.Variables{}
+	}
+
+	var buf bytes.Buffer
+	if err := tpl.Execute(&buf, map[string]any(vars)); err != nil {
+		return "", fmt.Errorf("failed to render template %q %s: %w", t.Name, part, err)
+	}
+	return buf.String(), nil
+}
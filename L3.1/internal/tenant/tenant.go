@@ -0,0 +1,24 @@
+package tenant
+
+import "context"
+
+type ctxKey struct{}
+
+// WithID возвращает контекст запроса, выполняемого от имени арендатора id
+func WithID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, ctxKey{}, id)
+}
+
+// FromContext возвращает арендатора запроса. Его нет у фоновой обработки,
+// у запросов с административным токеном и при выключенной авторизации —
+// такие запросы видят уведомления всех арендаторов.
+func FromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(ctxKey{}).(string)
+	return id, ok
+}
+
+// Allows сообщает, можно ли в контексте ctx работать с данными арендатора id
+func Allows(ctx context.Context, id string) bool {
+	current, ok := FromContext(ctx)
+	return !ok || current == id
+}
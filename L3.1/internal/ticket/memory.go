@@ -0,0 +1,58 @@
+package ticket
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryStore — хранилище билетов в памяти одного процесса: билет
+// погашается только на той реплике, которая его выдала
+type MemoryStore struct {
+	lock    sync.Mutex
+	pending map[string]entry
+}
+
+type entry struct {
+	grant   Grant
+	expires time.Time
+}
+
+var _ Store = (*MemoryStore)(nil)
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		pending: make(map[string]entry),
+	}
+}
+
+func (s *MemoryStore) Put(ctx context.Context, ticket string, g Grant, ttl time.Duration) (bool, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	// Истёкшие билеты удаляем здесь, заменяя TTL ключей Redis
+	now := time.Now()
+	for key, e := range s.pending {
+		if now.After(e.expires) {
+			delete(s.pending, key)
+		}
+	}
+
+	if _, ok := s.pending[ticket]; ok {
+		return false, nil
+	}
+	s.pending[ticket] = entry{grant: g, expires: now.Add(ttl)}
+	return true, nil
+}
+
+func (s *MemoryStore) Take(ctx context.Context, ticket string) (Grant, bool, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	e, ok := s.pending[ticket]
+	delete(s.pending, ticket)
+	if !ok || time.Now().After(e.expires) {
+		return Grant{}, false, nil
+	}
+	return e.grant, true, nil
+}
@@ -0,0 +1,24 @@
+package ticket
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryStoreExpiresTickets(t *testing.T) {
+	s := NewMemoryStore()
+	ctx := context.Background()
+
+	if ok, err := s.Put(ctx, "t1", Grant{TenantID: "acme", Scoped: true}, 10*time.Millisecond); err != nil || !ok {
+		t.Fatalf("Put = %v, %v", ok, err)
+	}
+	if ok, _ := s.Put(ctx, "t1", Grant{}, time.Minute); ok {
+		t.Fatal("Put overwrote a pending ticket")
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	if _, ok, err := s.Take(ctx, "t1"); err != nil || ok {
+		t.Fatalf("Take of an expired ticket = %v, %v; want not found", ok, err)
+	}
+}
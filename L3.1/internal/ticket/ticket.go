@@ -0,0 +1,70 @@
+package ticket
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	goredis "github.com/go-redis/redis/v8"
+	"github.com/wb-go/wbf/redis"
+)
+
+// Grant — права, с которыми будет открыт поток событий по билету
+type Grant struct {
+	TenantID string `json:"tenant_id"`
+	// Scoped — поток ограничен арендатором TenantID; без него (административный
+	// токен, выключенная авторизация) видны события всех арендаторов
+	Scoped bool `json:"scoped"`
+}
+
+// Store хранит выданные билеты до погашения.
+// Реализации: RedisStore (общий для реплик) и MemoryStore (в памяти процесса).
+type Store interface {
+	// Put сохраняет билет на ttl; false, если такой билет уже выдан
+	Put(ctx context.Context, ticket string, g Grant, ttl time.Duration) (bool, error)
+	// Take возвращает билет и удаляет его; false, если билета нет или он истёк
+	Take(ctx context.Context, ticket string) (Grant, bool, error)
+}
+
+const keyPrefix = "stream-ticket:"
+
+// RedisStore хранит билеты в Redis: билет, выданный одной репликой,
+// погашается на любой, куда балансировщик направит поток событий
+type RedisStore struct {
+	client *redis.Client
+}
+
+var _ Store = (*RedisStore)(nil)
+
+func NewRedisStore(client *redis.Client) *RedisStore {
+	return &RedisStore{
+		client: client,
+	}
+}
+
+func (s *RedisStore) Put(ctx context.Context, ticket string, g Grant, ttl time.Duration) (bool, error) {
+	data, err := json.Marshal(g)
+	if err != nil {
+		return false, err
+	}
+	return s.client.SetNX(ctx, keyPrefix+ticket, data, ttl).Result()
+}
+
+// Take читает и удаляет билет одной командой GETDEL, поэтому из двух
+// одновременных подключений с одним билетом проходит только одно
+func (s *RedisStore) Take(ctx context.Context, ticket string) (Grant, bool, error) {
+	data, err := s.client.GetDel(ctx, keyPrefix+ticket).Bytes()
+	if errors.Is(err, goredis.Nil) {
+		return Grant{}, false, nil
+	}
+	if err != nil {
+		return Grant{}, false, err
+	}
+
+	var g Grant
+	if err := json.Unmarshal(data, &g); err != nil {
+		return Grant{}, false, err
+	}
+	return g, true, nil
+}
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_notifications_series_id;
+ALTER TABLE notifications
+    DROP COLUMN IF EXISTS occurrence,
+    DROP COLUMN IF EXISTS series_id,
+    DROP COLUMN IF EXISTS max_occurrences,
+    DROP COLUMN IF EXISTS repeat_until,
+    DROP COLUMN IF EXISTS schedule;
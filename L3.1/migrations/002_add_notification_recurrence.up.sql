@@ -0,0 +1,9 @@
+ALTER TABLE notifications
+    ADD COLUMN IF NOT EXISTS schedule TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS repeat_until TIMESTAMP WITH TIME ZONE,
+    ADD COLUMN IF NOT EXISTS max_occurrences INT NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS series_id INT REFERENCES notifications (id) ON DELETE CASCADE,
+    ADD COLUMN IF NOT EXISTS occurrence INT NOT NULL DEFAULT 1;
+
+CREATE INDEX IF NOT EXISTS idx_notifications_series_id
+    ON notifications (series_id);
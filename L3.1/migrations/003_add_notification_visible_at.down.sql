@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_notifications_due;
+ALTER TABLE notifications
+    DROP COLUMN IF EXISTS visible_at;
@@ -0,0 +1,7 @@
+-- Момент, с которого строку может захватить Postgres-планировщик (NULL — send_at)
+ALTER TABLE notifications
+    ADD COLUMN IF NOT EXISTS visible_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_notifications_due
+    ON notifications (COALESCE(visible_at, send_at))
+    WHERE status = 0;
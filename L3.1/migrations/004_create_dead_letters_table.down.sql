@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_dead_letters_notification_id;
+DROP TABLE IF EXISTS dead_letters;
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS dead_letters (
+    id SERIAL PRIMARY KEY,
+    notification_id INT REFERENCES notifications (id) ON DELETE CASCADE,
+    reason TEXT NOT NULL,
+    payload TEXT NOT NULL,
+    failed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_dead_letters_notification_id
+    ON dead_letters (notification_id);
@@ -0,0 +1,4 @@
+ALTER TABLE notifications
+    DROP COLUMN IF EXISTS last_error;
+DROP INDEX IF EXISTS idx_notification_attempts_notification_id;
+DROP TABLE IF EXISTS notification_attempts;
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS notification_attempts (
+    id SERIAL PRIMARY KEY,
+    notification_id INT NOT NULL REFERENCES notifications (id) ON DELETE CASCADE,
+    channel SMALLINT NOT NULL,
+    attempted_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    duration_ms BIGINT NOT NULL,
+    error TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS idx_notification_attempts_notification_id
+    ON notification_attempts (notification_id);
+
+ALTER TABLE notifications
+    ADD COLUMN IF NOT EXISTS last_error TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,5 @@
+ALTER TABLE notifications
+    DROP COLUMN IF EXISTS variables,
+    DROP COLUMN IF EXISTS locale,
+    DROP COLUMN IF EXISTS template;
+DROP TABLE IF EXISTS templates;
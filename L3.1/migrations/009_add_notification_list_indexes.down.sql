@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_notifications_recipient_trgm;
+DROP INDEX IF EXISTS idx_notifications_channel;
+DROP INDEX IF EXISTS idx_notifications_user_created_at_id;
+DROP INDEX IF EXISTS idx_notifications_created_at_id;
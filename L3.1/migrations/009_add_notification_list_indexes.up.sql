@@ -0,0 +1,15 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- Keyset-пагинация списка
+CREATE INDEX IF NOT EXISTS idx_notifications_created_at_id
+    ON notifications (created_at DESC, id DESC);
+
+CREATE INDEX IF NOT EXISTS idx_notifications_user_created_at_id
+    ON notifications (user_id, created_at DESC, id DESC);
+
+CREATE INDEX IF NOT EXISTS idx_notifications_channel
+    ON notifications (channel);
+
+-- Поиск по подстроке получателя (ILIKE '%...%')
+CREATE INDEX IF NOT EXISTS idx_notifications_recipient_trgm
+    ON notifications USING gin (recipient gin_trgm_ops);
@@ -0,0 +1,3 @@
+ALTER TABLE notifications
+    DROP COLUMN IF EXISTS time_zone;
+DROP TABLE IF EXISTS user_profiles;
@@ -0,0 +1,4 @@
+ALTER TABLE notifications
+    DROP COLUMN IF EXISTS delivered_channel,
+    DROP COLUMN IF EXISTS target_index,
+    DROP COLUMN IF EXISTS fallbacks;
@@ -0,0 +1,4 @@
+ALTER TABLE notifications
+    ADD COLUMN IF NOT EXISTS fallbacks JSONB NOT NULL DEFAULT '[]',
+    ADD COLUMN IF NOT EXISTS target_index SMALLINT NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS delivered_channel SMALLINT;
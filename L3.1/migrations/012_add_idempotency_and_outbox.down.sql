@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS outbox;
+DROP INDEX IF EXISTS idx_notifications_idempotency_key;
+ALTER TABLE notifications
+    DROP COLUMN IF EXISTS idempotency_key;
@@ -0,0 +1,17 @@
+ALTER TABLE notifications
+    ADD COLUMN IF NOT EXISTS idempotency_key TEXT;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_notifications_idempotency_key
+    ON notifications (idempotency_key);
+
+CREATE TABLE IF NOT EXISTS outbox (
+    id BIGSERIAL PRIMARY KEY,
+    notification_id INT NOT NULL REFERENCES notifications (id) ON DELETE CASCADE,
+    payload TEXT NOT NULL,
+    publish_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    published_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_outbox_pending
+    ON outbox (id) WHERE published_at IS NULL;
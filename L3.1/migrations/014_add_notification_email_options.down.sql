@@ -0,0 +1,4 @@
+ALTER TABLE notifications
+    DROP COLUMN IF EXISTS email,
+    DROP COLUMN IF EXISTS html,
+    DROP COLUMN IF EXISTS subject;
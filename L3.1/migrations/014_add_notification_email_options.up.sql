@@ -0,0 +1,4 @@
+ALTER TABLE notifications
+    ADD COLUMN IF NOT EXISTS subject TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS html BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN IF NOT EXISTS email JSONB;
@@ -0,0 +1,9 @@
+ALTER TABLE user_profiles
+    DROP COLUMN IF EXISTS digest_window;
+
+DROP INDEX IF EXISTS idx_notifications_digest_candidates;
+
+ALTER TABLE notifications
+    DROP COLUMN IF EXISTS digest_id;
+
+DROP TABLE IF EXISTS digests;
@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS digests (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    channel SMALLINT NOT NULL,
+    recipient TEXT NOT NULL,
+    message TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    sent_at TIMESTAMP WITH TIME ZONE
+);
+
+ALTER TABLE notifications
+    ADD COLUMN IF NOT EXISTS digest_id BIGINT REFERENCES digests(id) ON DELETE SET NULL;
+
+-- Поиск уведомлений получателя, которые можно собрать в дайджест
+CREATE INDEX IF NOT EXISTS idx_notifications_digest_candidates
+    ON notifications (user_id, channel, recipient, send_at)
+    WHERE status = 0;
+
+ALTER TABLE user_profiles
+    ADD COLUMN IF NOT EXISTS digest_window TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS idx_notifications_tenant_created;
+
+DROP INDEX IF EXISTS idx_notifications_idempotency_key;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_notifications_idempotency_key
+    ON notifications (idempotency_key);
+
+ALTER TABLE notifications
+    DROP COLUMN IF EXISTS tenant_id;
+
+DROP TABLE IF EXISTS api_keys;
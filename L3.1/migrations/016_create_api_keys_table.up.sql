@@ -0,0 +1,27 @@
+CREATE TABLE IF NOT EXISTS api_keys (
+    id BIGSERIAL PRIMARY KEY,
+    tenant_id TEXT NOT NULL,
+    name TEXT NOT NULL DEFAULT '',
+    prefix TEXT NOT NULL,
+    key_hash TEXT NOT NULL UNIQUE,
+    scopes JSONB NOT NULL DEFAULT '[]',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    last_used_at TIMESTAMP WITH TIME ZONE,
+    revoked_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_keys_tenant
+    ON api_keys (tenant_id);
+
+-- Уведомления, созданные до появления арендаторов, принадлежат арендатору ''
+ALTER TABLE notifications
+    ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT '';
+
+-- Ключ идемпотентности уникален в пределах арендатора
+DROP INDEX IF EXISTS idx_notifications_idempotency_key;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_notifications_idempotency_key
+    ON notifications (tenant_id, idempotency_key);
+
+-- Keyset-пагинация списка арендатора
+CREATE INDEX IF NOT EXISTS idx_notifications_tenant_created
+    ON notifications (tenant_id, created_at DESC, id DESC);
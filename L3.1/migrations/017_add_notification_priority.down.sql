@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_notifications_priority_created;
+
+ALTER TABLE notifications
+    DROP COLUMN IF EXISTS priority;
@@ -0,0 +1,8 @@
+-- 1 — low, 2 — normal, 3 — high, 4 — critical
+ALTER TABLE notifications
+    ADD COLUMN IF NOT EXISTS priority SMALLINT NOT NULL DEFAULT 2
+        CHECK (priority BETWEEN 1 AND 4);
+
+-- Фильтр списка по приоритету
+CREATE INDEX IF NOT EXISTS idx_notifications_priority_created
+    ON notifications (priority, created_at DESC, id DESC);
@@ -0,0 +1,44 @@
+CREATE FUNCTION legacy_channel_number(name TEXT) RETURNS SMALLINT AS $$
+    SELECT CASE name
+        WHEN 'native' THEN 0
+        WHEN 'email' THEN 1
+        WHEN 'telegram' THEN 2
+        WHEN 'webhook' THEN 3
+    END
+$$ LANGUAGE SQL IMMUTABLE;
+
+-- Каналы без номера (sms и другие подключаемые) в старой схеме не представимы
+DELETE FROM notifications WHERE legacy_channel_number(channel) IS NULL;
+DELETE FROM templates WHERE legacy_channel_number(channel) IS NULL;
+DELETE FROM digests WHERE legacy_channel_number(channel) IS NULL;
+DELETE FROM notification_attempts WHERE legacy_channel_number(channel) IS NULL;
+
+UPDATE user_profiles
+SET preferred_channels = (
+    SELECT COALESCE(jsonb_agg(legacy_channel_number(c)) FILTER (WHERE legacy_channel_number(c) IS NOT NULL), '[]')
+    FROM jsonb_array_elements_text(preferred_channels) c
+)
+WHERE jsonb_array_length(preferred_channels) > 0;
+
+UPDATE notifications
+SET fallbacks = (
+    SELECT COALESCE(jsonb_agg(jsonb_set(f, '{channel}', to_jsonb(legacy_channel_number(f->>'channel'))))
+        FILTER (WHERE legacy_channel_number(f->>'channel') IS NOT NULL), '[]')
+    FROM jsonb_array_elements(fallbacks) f
+)
+WHERE jsonb_array_length(fallbacks) > 0;
+
+ALTER TABLE digests
+    ALTER COLUMN channel TYPE SMALLINT USING legacy_channel_number(channel);
+
+ALTER TABLE templates
+    ALTER COLUMN channel TYPE SMALLINT USING legacy_channel_number(channel);
+
+ALTER TABLE notification_attempts
+    ALTER COLUMN channel TYPE SMALLINT USING legacy_channel_number(channel);
+
+ALTER TABLE notifications
+    ALTER COLUMN channel TYPE SMALLINT USING legacy_channel_number(channel),
+    ALTER COLUMN delivered_channel TYPE SMALLINT USING legacy_channel_number(delivered_channel);
+
+DROP FUNCTION legacy_channel_number(TEXT);
@@ -0,0 +1,41 @@
+-- Каналы хранятся по имени: набор каналов задают зарегистрированные отправители
+CREATE FUNCTION legacy_channel_name(n INT) RETURNS TEXT AS $$
+    SELECT CASE n
+        WHEN 0 THEN 'native'
+        WHEN 1 THEN 'email'
+        WHEN 2 THEN 'telegram'
+        WHEN 3 THEN 'webhook'
+        ELSE n::TEXT
+    END
+$$ LANGUAGE SQL IMMUTABLE;
+
+ALTER TABLE notifications
+    ALTER COLUMN channel TYPE TEXT USING legacy_channel_name(channel),
+    ALTER COLUMN delivered_channel TYPE TEXT USING legacy_channel_name(delivered_channel);
+
+ALTER TABLE notification_attempts
+    ALTER COLUMN channel TYPE TEXT USING legacy_channel_name(channel);
+
+ALTER TABLE templates
+    ALTER COLUMN channel TYPE TEXT USING legacy_channel_name(channel);
+
+ALTER TABLE digests
+    ALTER COLUMN channel TYPE TEXT USING legacy_channel_name(channel);
+
+UPDATE notifications
+SET fallbacks = (
+    SELECT jsonb_agg(jsonb_set(f, '{channel}', to_jsonb(legacy_channel_name((f->>'channel')::INT))))
+    FROM jsonb_array_elements(fallbacks) f
+)
+WHERE jsonb_array_length(fallbacks) > 0
+AND jsonb_typeof(fallbacks->0->'channel') = 'number';
+
+UPDATE user_profiles
+SET preferred_channels = (
+    SELECT jsonb_agg(legacy_channel_name(c::INT))
+    FROM jsonb_array_elements_text(preferred_channels) c
+)
+WHERE jsonb_array_length(preferred_channels) > 0
+AND jsonb_typeof(preferred_channels->0) = 'number';
+
+DROP FUNCTION legacy_channel_name(INT);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_notifications_updated_at;
+DROP INDEX IF EXISTS idx_notifications_status_id;
@@ -0,0 +1,6 @@
+-- Сверка кэша: запланированные уведомления и недавно изменённые
+CREATE INDEX IF NOT EXISTS idx_notifications_status_id
+    ON notifications (status, id);
+
+CREATE INDEX IF NOT EXISTS idx_notifications_updated_at
+    ON notifications (updated_at);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_outbox_published_at;
+DROP INDEX IF EXISTS idx_notifications_queued_until;
+
+ALTER TABLE notifications
+    DROP COLUMN IF EXISTS queued_until;
@@ -0,0 +1,18 @@
+-- queued_until — когда ожидается обработка сообщения, последним опубликованного
+-- для уведомления. Запланированное уведомление, не обработанное долго после
+-- этого времени, считается потерянным брокером и публикуется заново.
+ALTER TABLE notifications
+    ADD COLUMN IF NOT EXISTS queued_until TIMESTAMP WITH TIME ZONE;
+
+-- Для уже запланированных уведомлений неизвестно, не отложены ли они повтором
+-- или тихими часами, поэтому проверка начнётся не раньше чем через сутки
+UPDATE notifications
+SET queued_until = GREATEST(send_at, NOW() + INTERVAL '1 day')
+WHERE status = 0 AND queued_until IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_notifications_queued_until
+    ON notifications (queued_until) WHERE status = 0;
+
+-- Для удаления опубликованных сообщений outbox по сроку хранения
+CREATE INDEX IF NOT EXISTS idx_outbox_published_at
+    ON outbox (published_at) WHERE published_at IS NOT NULL;
@@ -0,0 +1,20 @@
+DROP INDEX IF EXISTS idx_notifications_digest_candidates;
+CREATE INDEX IF NOT EXISTS idx_notifications_digest_candidates
+    ON notifications (user_id, channel, recipient, send_at)
+    WHERE status = 0;
+
+ALTER TABLE digests
+    DROP COLUMN IF EXISTS tenant_id;
+
+-- Без арендатора у пользователя остаётся один профиль — самый свежий
+DELETE FROM user_profiles p
+USING user_profiles newer
+WHERE newer.user_id = p.user_id
+AND (newer.updated_at, newer.tenant_id) > (p.updated_at, p.tenant_id);
+
+ALTER TABLE user_profiles
+    DROP CONSTRAINT IF EXISTS user_profiles_pkey,
+    ADD PRIMARY KEY (user_id);
+
+ALTER TABLE user_profiles
+    DROP COLUMN IF EXISTS tenant_id;
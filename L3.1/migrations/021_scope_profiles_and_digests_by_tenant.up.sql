@@ -0,0 +1,22 @@
+-- Профили и дайджесты, созданные до появления арендаторов, принадлежат арендатору ''
+ALTER TABLE user_profiles
+    ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT '';
+
+-- Идентификатор пользователя уникален только в пределах арендатора
+ALTER TABLE user_profiles
+    DROP CONSTRAINT IF EXISTS user_profiles_pkey,
+    ADD PRIMARY KEY (tenant_id, user_id);
+
+ALTER TABLE digests
+    ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT '';
+
+UPDATE digests d
+SET tenant_id = n.tenant_id
+FROM notifications n
+WHERE n.digest_id = d.id AND d.tenant_id = '';
+
+-- Дайджест собирается только из уведомлений одного арендатора
+DROP INDEX IF EXISTS idx_notifications_digest_candidates;
+CREATE INDEX IF NOT EXISTS idx_notifications_digest_candidates
+    ON notifications (tenant_id, user_id, channel, recipient, send_at)
+    WHERE status = 0;
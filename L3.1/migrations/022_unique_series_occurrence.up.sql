@@ -0,0 +1,17 @@
+-- Два воркера могли одновременно создать одно и то же следующее срабатывание серии.
+-- Оставляем по одному: уже обработанное, а среди прочих — созданное первым.
+DELETE FROM notifications n
+USING (
+    SELECT id, ROW_NUMBER() OVER (
+        PARTITION BY series_id, occurrence
+        ORDER BY (status = 0), id
+    ) AS rn
+    FROM notifications
+    WHERE series_id IS NOT NULL
+) d
+WHERE n.id = d.id AND d.rn > 1;
+
+-- Первое срабатывание хранит series_id = NULL, остальные ссылаются на него
+CREATE UNIQUE INDEX IF NOT EXISTS idx_notifications_series_occurrence
+    ON notifications (series_id, occurrence)
+    WHERE series_id IS NOT NULL;
@@ -46,11 +46,21 @@ func main() {
 
 	// Сервис
 	shortService := service.NewShortenerService(shortRepo, analyticsRepo, redisCache, *sqidsGen)
-	ctx := context.Background()
+	ctx, stopArchiver := context.WithCancel(context.Background())
+	defer stopArchiver()
 	shortService.RestoreCacheFromDB(ctx)
 
+	// Архивация истёкших ссылок
+	go shortService.RunArchiver(ctx, cfg.ArchiveInterval)
+
+	// Страница для истёкших ссылок
+	gonePage, err := os.ReadFile(cfg.ExpiredPage)
+	if err != nil {
+		log.Fatalf("Failed to read expired page: %v", err)
+	}
+
 	// Хендлер
-	h := handler.NewURLHandler(shortService)
+	h := handler.NewURLHandler(shortService, gonePage)
 
 	// Gin Engine
 	r := ginext.New("")
@@ -80,6 +90,7 @@ func main() {
 
 	<-quit
 	log.Println("Shutting down server...")
+	stopArchiver()
 
 	ctxShutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
@@ -32,8 +32,17 @@ func (c *URLCache) Set(ctx context.Context, url *models.ShortURL) error {
 		return err
 	}
 
+	// Ссылка со сроком действия не должна пережить его в кэше
+	ttl := time.Hour
+	if url.ExpiresAt != nil {
+		ttl = min(ttl, time.Until(*url.ExpiresAt))
+		if ttl <= 0 {
+			return nil
+		}
+	}
+
 	key := url.ShortCode
-	return c.client.SetWithExpiration(ctx, key, data, time.Hour)
+	return c.client.SetWithExpiration(ctx, key, data, ttl)
 }
 
 func (c *URLCache) Get(ctx context.Context, code string) (*models.ShortURL, error) {
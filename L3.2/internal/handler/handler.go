@@ -1,7 +1,9 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
+	"time"
 
 	"shortener/internal/service"
 
@@ -10,17 +12,21 @@ import (
 
 type Handler struct {
 	service *service.ShortenerService
+	// gonePage — страница, которую видит пользователь истёкшей ссылки
+	gonePage []byte
 }
 
-func NewURLHandler(s *service.ShortenerService) *Handler {
-	return &Handler{service: s}
+func NewURLHandler(s *service.ShortenerService, gonePage []byte) *Handler {
+	return &Handler{service: s, gonePage: gonePage}
 }
 
 // POST /shorten
 func (h *Handler) Shorten(c *ginext.Context) {
 	var req struct {
-		URL    string `json:"url"`
-		Custom string `json:"custom,omitempty"`
+		URL       string     `json:"url"`
+		Custom    string     `json:"custom,omitempty"`
+		ExpiresAt *time.Time `json:"expires_at,omitempty"`
+		MaxClicks int        `json:"max_clicks,omitempty"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -30,7 +36,12 @@ func (h *Handler) Shorten(c *ginext.Context) {
 
 	ctx := c.Request.Context()
 
-	shortURL, err := h.service.Create(ctx, req.URL, req.Custom)
+	limits := service.Limits{ExpiresAt: req.ExpiresAt, MaxClicks: req.MaxClicks}
+	shortURL, err := h.service.Create(ctx, req.URL, req.Custom, limits)
+	if errors.Is(err, service.ErrInvalidLimits) {
+		c.JSON(http.StatusBadRequest, ginext.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, ginext.H{"error": err.Error()})
 		return
@@ -45,6 +56,10 @@ func (h *Handler) Redirect(c *ginext.Context) {
 	ctx := c.Request.Context()
 
 	originalURL, err := h.service.Resolve(ctx, shortCode, c.Request.UserAgent())
+	if errors.Is(err, service.ErrLinkGone) {
+		c.Data(http.StatusGone, "text/html; charset=utf-8", h.gonePage)
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusNotFound, ginext.H{"error": "short url not found"})
 		return
@@ -6,6 +6,15 @@ async function createShort() {
         .getElementById("customCode")
         .value.trim();
     if (!url) return alert("Введите URL");
+    const expires = document.getElementById("expiresAt").value;
+    const maxClicks = parseInt(
+        document.getElementById("maxClicks").value || 0,
+        10
+    );
+    const payload = { url, custom };
+    // datetime-local задаётся в локальном времени, сервер ждёт RFC 3339
+    if (expires) payload.expires_at = new Date(expires).toISOString();
+    if (maxClicks > 0) payload.max_clicks = maxClicks;
     const btn = document.getElementById("createBtn");
     btn.disabled = true;
     btn.textContent = "...";
@@ -13,7 +22,7 @@ async function createShort() {
         const res = await fetch(apiBase + "/shorten", {
             method: "POST",
             headers: { "Content-Type": "application/json" },
-            body: JSON.stringify({ url, custom }),
+            body: JSON.stringify(payload),
         });
         if (!res.ok) {
             const txt = await res.text();
@@ -113,9 +122,10 @@ function renderLatest(items) {
             "";
         const node = document.createElement("div");
         node.className = "link-item";
+        const limits = describeLimits(it);
         node.innerHTML = `<div class="link-left"><div class="short-badge">${code}</div><div><div style="font-weight:600">${original}</div><div class="muted small">${
             created ? dayjs(created).format("YYYY-MM-DD HH:mm") : ""
-        }</div></div></div>
+        }${limits ? " · " + limits : ""}</div></div></div>
       <div class="actions">
         <button class="btn-ghost" onclick="openInNew('${
             window.location.origin + "/s/" + code
@@ -126,6 +136,22 @@ function renderLatest(items) {
     });
 }
 
+// describeLimits описывает срок действия и лимит переходов ссылки
+function describeLimits(it) {
+    if (it.ArchivedAt) return "в архиве";
+    const parts = [];
+    if (it.ExpiresAt) {
+        const expires = dayjs(it.ExpiresAt);
+        parts.push(
+            (expires.isBefore(dayjs()) ? "истекла " : "до ") +
+                expires.format("YYYY-MM-DD HH:mm")
+        );
+    }
+    if (it.MaxClicks > 0)
+        parts.push(`переходов ${it.Clicks || 0}/${it.MaxClicks}`);
+    return parts.join(" · ");
+}
+
 function openInNew(u) {
     window.open(u, "_blank");
 }
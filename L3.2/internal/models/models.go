@@ -7,6 +7,25 @@ type ShortURL struct {
 	ShortCode string
 	Original  string
 	CreatedAt time.Time
+	// Ссылка перестаёт работать после ExpiresAt (если задан)
+	// или после MaxClicks переходов (0 — без ограничения)
+	ExpiresAt *time.Time
+	MaxClicks int
+	// Clicks — число переходов, учитывается только для ссылок с MaxClicks
+	Clicks int
+	// ArchivedAt — когда истёкшая ссылка убрана в архив
+	ArchivedAt *time.Time
+}
+
+// Expired сообщает, истекла ли ссылка к моменту now
+func (u *ShortURL) Expired(now time.Time) bool {
+	if u.ArchivedAt != nil {
+		return true
+	}
+	if u.ExpiresAt != nil && !now.Before(*u.ExpiresAt) {
+		return true
+	}
+	return u.MaxClicks > 0 && u.Clicks >= u.MaxClicks
 }
 
 type ClickEvent struct {
@@ -15,4 +34,3 @@ type ClickEvent struct {
 	UserAgent string
 	Timestamp time.Time
 }
-
@@ -2,6 +2,8 @@ package repository
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 
 	. "shortener/internal/models"
 
@@ -13,6 +15,11 @@ type ShortURLRepository interface {
 	FindByID(ctx context.Context, ShortCode string) (*ShortURL, error)
 	FindTopPopular(ctx context.Context, limit int) ([]ShortURL, error)
 	ListLatest(ctx context.Context, limit int) ([]ShortURL, error)
+	// ConsumeClick засчитывает переход по ссылке с ограничением MaxClicks.
+	// false — переходы исчерпаны или ссылка уже в архиве.
+	ConsumeClick(ctx context.Context, id int) (bool, error)
+	// ArchiveExpired переносит в архив до limit ссылок с истёкшим сроком и возвращает их коды
+	ArchiveExpired(ctx context.Context, limit int) ([]string, error)
 }
 
 type AnalyticsRepository interface {
@@ -40,23 +47,85 @@ func NewAnalyticsRepo(db *dbpg.DB) AnalyticsRepository {
 	}
 }
 
+// shortURLColumns — порядок колонок, который ожидает scanShortURL
+const shortURLColumns = `id, short_code, original, created_at, expires_at, max_clicks, clicks, archived_at`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanShortURL(row rowScanner) (ShortURL, error) {
+	var u ShortURL
+	err := row.Scan(&u.ID, &u.ShortCode, &u.Original, &u.CreatedAt, &u.ExpiresAt, &u.MaxClicks, &u.Clicks, &u.ArchivedAt)
+	return u, err
+}
+
 func (r *shortURLRepo) Save(ctx context.Context, u *ShortURL) error {
-	query := `INSERT INTO short_urls (short_code, original) 
-	VALUES ($1, $2) 
+	query := `INSERT INTO short_urls (short_code, original, expires_at, max_clicks) 
+	VALUES ($1, $2, $3, $4) 
 	RETURNING id, created_at`
-	return r.DB.QueryRowContext(ctx, query, u.ShortCode, u.Original).Scan(&u.ID, &u.CreatedAt)
+	return r.DB.QueryRowContext(ctx, query, u.ShortCode, u.Original, u.ExpiresAt, u.MaxClicks).Scan(&u.ID, &u.CreatedAt)
 }
 
 func (r *shortURLRepo) FindByID(ctx context.Context, code string) (*ShortURL, error) {
-	query := `SELECT id, original FROM short_urls WHERE short_code = $1;`
-	var u ShortURL
-	err := r.DB.QueryRowContext(ctx, query, code).Scan(&u.ID, &u.Original)
+	query := `SELECT ` + shortURLColumns + ` FROM short_urls WHERE short_code = $1;`
+	u, err := scanShortURL(r.DB.QueryRowContext(ctx, query, code))
 	if err != nil {
 		return nil, err
 	}
 	return &u, err
 }
 
+func (r *shortURLRepo) ConsumeClick(ctx context.Context, id int) (bool, error) {
+	// Последний разрешённый переход сразу отправляет ссылку в архив
+	query := `
+		UPDATE short_urls
+		SET clicks = clicks + 1,
+			archived_at = CASE WHEN clicks + 1 >= max_clicks THEN NOW() END
+		WHERE id = $1
+		AND archived_at IS NULL
+		AND clicks < max_clicks
+		AND (expires_at IS NULL OR expires_at > NOW())
+		RETURNING id;
+	`
+	err := r.DB.QueryRowContext(ctx, query, id).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (r *shortURLRepo) ArchiveExpired(ctx context.Context, limit int) ([]string, error) {
+	query := `
+		UPDATE short_urls
+		SET archived_at = NOW()
+		WHERE id IN (
+			SELECT id FROM short_urls
+			WHERE archived_at IS NULL
+			AND expires_at <= NOW()
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING short_code;
+	`
+
+	rows, err := r.DB.QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var codes []string
+	for rows.Next() {
+		var code string
+		if err := rows.Scan(&code); err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+	}
+	return codes, rows.Err()
+}
+
 func (r *analyticsRepo) Save(ctx context.Context, u *ClickEvent) error {
 	query := `INSERT INTO click_events (short_url_id, user_agent) VALUES ($1, $2)`
 	_, err := r.DB.ExecContext(ctx, query, u.ShortID, u.UserAgent)
@@ -93,9 +162,16 @@ func (r *shortURLRepo) FindTopPopular(ctx context.Context, limit int) ([]ShortUR
 			su.id,
 			su.short_code,
 			su.original,
+			su.created_at,
+			su.expires_at,
+			su.max_clicks,
+			su.clicks,
+			su.archived_at,
 			COUNT(ce.id) AS click_count
 		FROM short_urls su
 		LEFT JOIN click_events ce ON ce.short_url_id = su.id
+		WHERE su.archived_at IS NULL
+		AND (su.expires_at IS NULL OR su.expires_at > NOW())
 		GROUP BY su.id
 		ORDER BY click_count DESC
 		LIMIT $1;
@@ -111,7 +187,7 @@ func (r *shortURLRepo) FindTopPopular(ctx context.Context, limit int) ([]ShortUR
 	for rows.Next() {
 		var u ShortURL
 		var clickCount int64
-		if err := rows.Scan(&u.ID, &u.ShortCode, &u.Original, &clickCount); err != nil {
+		if err := rows.Scan(&u.ID, &u.ShortCode, &u.Original, &u.CreatedAt, &u.ExpiresAt, &u.MaxClicks, &u.Clicks, &u.ArchivedAt, &clickCount); err != nil {
 			return nil, err
 		}
 		urls = append(urls, u)
@@ -126,7 +202,7 @@ func (r *shortURLRepo) FindTopPopular(ctx context.Context, limit int) ([]ShortUR
 
 func (r *shortURLRepo) ListLatest(ctx context.Context, limit int) ([]ShortURL, error) {
 	query := `
-		SELECT id, short_code, original, created_at
+		SELECT ` + shortURLColumns + `
 		FROM short_urls
 		ORDER BY created_at DESC
 		LIMIT $1;
@@ -141,8 +217,8 @@ func (r *shortURLRepo) ListLatest(ctx context.Context, limit int) ([]ShortURL, e
 	var result []ShortURL
 
 	for rows.Next() {
-		var s ShortURL
-		if err := rows.Scan(&s.ID, &s.ShortCode, &s.Original, &s.CreatedAt); err != nil {
+		s, err := scanShortURL(rows)
+		if err != nil {
 			return nil, err
 		}
 		result = append(result, s)
@@ -2,8 +2,10 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"time"
 
 	"shortener/internal/cache"
 	"shortener/internal/generator"
@@ -24,11 +26,40 @@ func NewShortenerService(s ShortURLRepository, a AnalyticsRepository, c cache.Ca
 	return &ShortenerService{s, a, c, g}
 }
 
-func (s *ShortenerService) Create(ctx context.Context, original, customCode string) (*models.ShortURL, error) {
+var (
+	// ErrInvalidLimits — срок действия или число переходов заданы неверно
+	ErrInvalidLimits = errors.New("invalid link limits")
+	// ErrLinkGone — срок действия ссылки истёк или переходы исчерпаны
+	ErrLinkGone = errors.New("short url has expired")
+)
+
+// Limits — необязательные ограничения ссылки: срок действия и число переходов
+type Limits struct {
+	ExpiresAt *time.Time
+	MaxClicks int
+}
+
+func (l Limits) validate() error {
+	if l.ExpiresAt != nil && !l.ExpiresAt.After(time.Now()) {
+		return fmt.Errorf("%w: expires_at must be in the future", ErrInvalidLimits)
+	}
+	if l.MaxClicks < 0 {
+		return fmt.Errorf("%w: max_clicks must not be negative", ErrInvalidLimits)
+	}
+	return nil
+}
+
+func (s *ShortenerService) Create(ctx context.Context, original, customCode string, limits Limits) (*models.ShortURL, error) {
+	if err := limits.validate(); err != nil {
+		return nil, err
+	}
+
 	if customCode != "" {
 		url := &models.ShortURL{
 			ShortCode: customCode,
 			Original:  original,
+			ExpiresAt: limits.ExpiresAt,
+			MaxClicks: limits.MaxClicks,
 		}
 
 		err := s.shortRepo.Save(ctx, url)
@@ -48,6 +79,8 @@ func (s *ShortenerService) Create(ctx context.Context, original, customCode stri
 		url := &models.ShortURL{
 			ShortCode: code,
 			Original:  original,
+			ExpiresAt: limits.ExpiresAt,
+			MaxClicks: limits.MaxClicks,
 		}
 
 		err := s.shortRepo.Save(ctx, url)
@@ -69,20 +102,38 @@ func isUniqueViolation(err error) bool {
 	return ok && pgErr.Code == "23505"
 }
 
+// Resolve возвращает оригинальный URL и засчитывает переход.
+// Для истёкшей ссылки возвращает ErrLinkGone.
 func (s *ShortenerService) Resolve(ctx context.Context, code, ua string) (string, error) {
-	if cached, err := s.cache.Get(ctx, code); err != nil {
+	url, err := s.cache.Get(ctx, code)
+	if err != nil {
 		return "", err
-	} else if cached != nil {
-		s.recordClick(cached.ID, ua)
-		return cached.Original, nil
+	}
+	if url == nil {
+		if url, err = s.shortRepo.FindByID(ctx, code); err != nil {
+			return "", err
+		}
+		if !url.Expired(time.Now()) {
+			_ = s.cache.Set(ctx, url)
+		}
 	}
 
-	url, err := s.shortRepo.FindByID(ctx, code)
-	if err != nil {
-		return "", err
+	if url.Expired(time.Now()) {
+		_ = s.cache.Delete(ctx, code)
+		return "", ErrLinkGone
 	}
 
-	_ = s.cache.Set(ctx, url)
+	// Переходы по ссылке с ограничением считаются в базе: кэш общий для всех реплик
+	if url.MaxClicks > 0 {
+		ok, err := s.shortRepo.ConsumeClick(ctx, url.ID)
+		if err != nil {
+			return "", err
+		}
+		if !ok {
+			_ = s.cache.Delete(ctx, code)
+			return "", ErrLinkGone
+		}
+	}
 
 	s.recordClick(url.ID, ua)
 	return url.Original, nil
@@ -105,6 +156,50 @@ func (s *ShortenerService) ListLatest(ctx context.Context) ([]models.ShortURL, e
 	return s.shortRepo.ListLatest(ctx, 100)
 }
 
+// archiveBatch — сколько ссылок архивировать за один запрос
+const archiveBatch = 500
+
+// ArchiveExpired переносит в архив ссылки с истёкшим сроком и убирает их из кэша
+func (s *ShortenerService) ArchiveExpired(ctx context.Context) (int, error) {
+	total := 0
+	for {
+		codes, err := s.shortRepo.ArchiveExpired(ctx, archiveBatch)
+		if err != nil {
+			return total, err
+		}
+		for _, code := range codes {
+			if err := s.cache.Delete(ctx, code); err != nil {
+				log.Printf("не удалось удалить из кэша %s: %v", code, err)
+			}
+		}
+
+		total += len(codes)
+		if len(codes) < archiveBatch {
+			return total, nil
+		}
+	}
+}
+
+// RunArchiver архивирует истёкшие ссылки раз в interval, пока не отменён ctx
+func (s *ShortenerService) RunArchiver(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := s.ArchiveExpired(ctx)
+			if err != nil {
+				log.Printf("не удалось архивировать истёкшие ссылки: %v", err)
+			} else if n > 0 {
+				log.Printf("в архив перенесено %d ссылок", n)
+			}
+		}
+	}
+}
+
 func (s *ShortenerService) RestoreCacheFromDB(ctx context.Context) error {
 	urls, err := s.shortRepo.FindTopPopular(ctx, 100)
 	if err != nil {
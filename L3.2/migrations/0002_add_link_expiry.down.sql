@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_short_urls_expires_at;
+
+ALTER TABLE short_urls
+    DROP COLUMN IF EXISTS archived_at,
+    DROP COLUMN IF EXISTS clicks,
+    DROP COLUMN IF EXISTS max_clicks,
+    DROP COLUMN IF EXISTS expires_at;
@@ -0,0 +1,10 @@
+ALTER TABLE short_urls
+    ADD COLUMN IF NOT EXISTS expires_at  TIMESTAMP WITH TIME ZONE,
+    ADD COLUMN IF NOT EXISTS max_clicks  INT NOT NULL DEFAULT 0 CHECK (max_clicks >= 0),
+    ADD COLUMN IF NOT EXISTS clicks      INT NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS archived_at TIMESTAMP WITH TIME ZONE;
+
+-- Поиск истёкших ссылок фоновой архивацией
+CREATE INDEX IF NOT EXISTS idx_short_urls_expires_at
+    ON short_urls(expires_at)
+    WHERE archived_at IS NULL AND expires_at IS NOT NULL;